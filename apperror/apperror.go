@@ -0,0 +1,177 @@
+package apperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// StatusClientClosedRequest is the non standard status used when the client went away before the response
+const StatusClientClosedRequest = 499
+
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidQuery     = "invalid_query"
+	CodeValidationFailed = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeRequestCanceled  = "request_canceled"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal_error"
+)
+
+// FieldError describes what is wrong with a single field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error that knows how it is presented to clients, Err holds the cause and is never sent
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(code string, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+func NotFound(code string, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+func Conflict(code string, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+// InvalidQuery reports query string parameters that cannot be used
+func InvalidQuery(fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidQuery, Message: "invalid query parameters", Fields: fields}
+}
+
+// Validation reports a well formed request body whose values break the rules
+func Validation(fields ...FieldError) *Error {
+	return &Error{Status: http.StatusUnprocessableEntity, Code: CodeValidationFailed, Message: "validation failed", Fields: fields}
+}
+
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error", Err: err}
+}
+
+// Wrap keeps err as the cause of e, so it can still be matched with errors.Is
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+
+	return &wrapped
+}
+
+// From classifies any error returned to a controller, unknown errors become an opaque internal error
+func From(err error) *Error {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, context.Canceled):
+		return &Error{Status: StatusClientClosedRequest, Code: CodeRequestCanceled, Message: "request canceled", Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Message: "request timed out", Err: err}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "resource not found", Err: err}
+	}
+
+	return Internal(err)
+}
+
+// init makes gin's validator name fields the way clients send them, so FromBinding reports userId rather than UserID
+func init() {
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName is the json name of a field, or its form name for fields only bound from a query
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// FromBinding converts an error from gin's ShouldBind functions
+func FromBinding(err error) *Error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return BadRequest(CodeInvalidJSON, "request body is empty").Wrap(err)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest(CodeInvalidJSON, "request body is not valid json").Wrap(err)
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return BadRequest(CodeInvalidJSON, "request body has an unexpected shape").Wrap(err)
+		}
+
+		return Validation(FieldError{
+			Field:   field,
+			Code:    "invalid_type",
+			Message: field + " must be of type " + typeErr.Type.String(),
+		}).Wrap(err)
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   lowerFirst(fieldErr.Field()),
+				Code:    fieldErr.Tag(),
+				Message: lowerFirst(fieldErr.Field()) + " failed the " + fieldErr.Tag() + " rule",
+			})
+		}
+
+		return Validation(fields...).Wrap(err)
+	}
+
+	return BadRequest(CodeInvalidRequest, "request could not be parsed").Wrap(err)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,61 @@
+package apperror_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/apperror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromBindingFieldNames(t *testing.T) {
+	type payload struct {
+		UserID   uint   `json:"userId,omitempty" binding:"required"`
+		Currency string `json:"currency" binding:"len=3"`
+		Timezone string `form:"tz" json:"-" binding:"required"`
+		Note     string `binding:"required"`
+	}
+
+	testCases := map[string]struct {
+		url            string
+		body           string
+		bind           func(c *gin.Context, target *payload) error
+		expectedFields []apperror.FieldError
+	}{
+		"json names of a body": {
+			url:  "/",
+			body: `{"currency":"RUPIAH"}`,
+			bind: func(c *gin.Context, target *payload) error { return c.ShouldBindJSON(target) },
+			expectedFields: []apperror.FieldError{
+				{Field: "userId", Code: "required", Message: "userId failed the required rule"},
+				{Field: "currency", Code: "len", Message: "currency failed the len rule"},
+				{Field: "tz", Code: "required", Message: "tz failed the required rule"},
+				{Field: "note", Code: "required", Message: "note failed the required rule"},
+			},
+		},
+		"form name of a query field without a json name": {
+			url:  "/?UserID=1&Currency=IDR&Note=a",
+			bind: func(c *gin.Context, target *payload) error { return c.ShouldBindQuery(target) },
+			expectedFields: []apperror.FieldError{
+				{Field: "tz", Code: "required", Message: "tz failed the required rule"},
+			},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest(http.MethodPost, test.url, strings.NewReader(test.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var target payload
+			err := apperror.FromBinding(test.bind(c, &target))
+
+			assert.Equal(t, http.StatusUnprocessableEntity, err.Status)
+			assert.Equal(t, test.expectedFields, err.Fields)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Config holds the settings subcommands share with the api
+type Config struct {
+	DeletedRetention time.Duration
+}
+
+// Run executes the subcommand named by the first argument
+func Run(db *gorm.DB, config Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "migrate":
+		return Migrate(db, args[1:])
+	case "purge":
+		return Purge(db, config, args[1:])
+	case "import":
+		return Import(db, config, args[1:])
+	case "ledger":
+		return Ledger(db, args[1:])
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
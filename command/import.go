@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	importUsage = "usage: import transactions <file.csv> [-dry-run] [-column field=header]... [-report errors.csv]"
+
+	// importActor is recorded on the events of transactions imported from the command line
+	importActor = "command"
+)
+
+// columnFlag collects repeated -column field=header flags
+type columnFlag map[string]string
+
+func (f columnFlag) String() string {
+	return fmt.Sprint(map[string]string(f))
+}
+
+func (f columnFlag) Set(value string) error {
+	field, header, ok := strings.Cut(value, "=")
+	if !ok || field == "" || header == "" {
+		return fmt.Errorf("column must look like field=header, got %q", value)
+	}
+
+	f[field] = header
+	return nil
+}
+
+// Import creates transactions from a csv file with the same rules as the import endpoint, the rows that failed
+// are printed or written to the report file
+func Import(db *gorm.DB, config Config, args []string) error {
+	if len(args) < 2 || args[0] != "transactions" {
+		return errors.New(importUsage)
+	}
+
+	columns := columnFlag{}
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "validate the rows without creating them")
+	report := flags.String("report", "", "write the rows that failed to this csv file")
+	flags.Var(columns, "column", "read a field from another csv column")
+	if err := flags.Parse(args[2:]); err != nil || flags.NArg() > 0 {
+		return errors.New(importUsage)
+	}
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	service := transactionservice.NewTransactionService(
+		repository.NewDatabaseRepository[model.Transaction](db),
+		repository.NewDatabaseRepository[model.User](db),
+		repository.NewDatabaseRepository[model.TransactionEvent](db),
+		repository.NewTransactor(db),
+		ledger.NewPostgresLedger(db),
+	)
+	result, err := service.Import(context.Background(), file, transactionservice.ImportOptions{
+		Columns: columns,
+		DryRun:  *dryRun,
+		Actor:   importActor,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.DryRun {
+		fmt.Printf("checked %d row(s): %d valid, %d failed, nothing imported\n", result.Rows, result.Valid, len(result.Errors))
+	} else {
+		fmt.Printf("imported %d of %d row(s), %d failed\n", result.Imported, result.Rows, len(result.Errors))
+	}
+	if len(result.Errors) == 0 {
+		return nil
+	}
+
+	if *report == "" {
+		return result.WriteReport(os.Stdout)
+	}
+
+	reportFile, err := os.Create(*report)
+	if err != nil {
+		return err
+	}
+	defer reportFile.Close()
+
+	if err := result.WriteReport(reportFile); err != nil {
+		return err
+	}
+	fmt.Printf("wrote the failed row(s) to %s\n", *report)
+	return nil
+}
@@ -0,0 +1,33 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-findest-rest-api/ledger"
+	"gorm.io/gorm"
+)
+
+const ledgerUsage = "usage: ledger check"
+
+// Ledger checks that the postings of every journal entry sum to zero, it fails when one does not
+func Ledger(db *gorm.DB, args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return errors.New(ledgerUsage)
+	}
+
+	report, err := ledger.NewPostgresLedger(db).Check(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, unbalanced := range report.Unbalanced {
+		fmt.Printf("journal %d (%s): %d posting(s) in %q sum to %d\n", unbalanced.JournalEntryID, unbalanced.Reference, unbalanced.Postings, unbalanced.Currency, unbalanced.Sum)
+	}
+	if len(report.Unbalanced) > 0 {
+		return fmt.Errorf("%d of %d journal(s) do not balance", len(report.Unbalanced), report.Journals)
+	}
+
+	fmt.Printf("checked %d journal(s), all balanced\n", report.Journals)
+	return nil
+}
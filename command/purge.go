@@ -0,0 +1,41 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"gorm.io/gorm"
+	"time"
+)
+
+const purgeUsage = "usage: purge transactions [older than, such as 720h]"
+
+// Purge permanently removes transactions deleted longer ago than the retention, or than the given duration.
+// Settled transactions are kept, the ledger holds the journals of their payment
+func Purge(db *gorm.DB, config Config, args []string) error {
+	if len(args) == 0 || args[0] != "transactions" || len(args) > 2 {
+		return errors.New(purgeUsage)
+	}
+
+	retention := config.DeletedRetention
+	if len(args) == 2 {
+		var err error
+		retention, err = time.ParseDuration(args[1])
+		if err != nil || retention < 0 {
+			return fmt.Errorf("invalid duration %q", args[1])
+		}
+	}
+
+	deletedBefore := time.Now().Add(-retention)
+	purged, err := repository.NewDatabaseRepository[model.Transaction](db).Purge(context.Background(), deletedBefore, repository.Filter{
+		Where: repository.NotIn("status", model.SettledStatuses()),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d transaction(s) deleted before %s\n", purged, deletedBefore.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -1,10 +1,13 @@
 package dashboardcontroller
 
 import (
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
 	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
 	"go-findest-rest-api/repository"
 	"go-findest-rest-api/util"
 	"time"
@@ -26,25 +29,58 @@ func NewDashboardController(
 }
 
 func (dc *DashboardController) GetDashboardSummary(c *gin.Context) {
+	// bind query into struct
+	var query dto.GetDashboardQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
 	// build date filter
-	today := time.Now().Format("2006-01-02")
-	dateFilter := fmt.Sprintf("AND status = 'success' AND updated_at BETWEEN '%s 00:00:00' AND '%s 23:59:59'", today, today)
+	window, err := parseDateRange(query, time.Now())
+	if err != nil {
+		util.Error(c, err)
+		return
+	}
+	// a transaction succeeded within the window when its payment journal was posted in it, a refund later on
+	// changes updated_at but not when it was paid
+	successfulFilter := repository.Filter{
+		Where: repository.All(
+			repository.In("status", []string{model.StatusSuccess, model.StatusPartiallyRefunded}),
+			repository.InQuery("id", ledger.JournalEntry{}.TableName(), "transaction_id", repository.All(
+				repository.Eq("type", ledger.JournalPayment),
+				window.Where("created_at"),
+			)),
+		),
+	}
+	createdFilter := repository.Filter{Where: window.Where("created_at")}
+	latestFilter := repository.Filter{
+		Where: window.Where("created_at"),
+		Sort:  []repository.Sort{{Field: "created_at", Descending: true}},
+		Limit: 10,
+	}
 
 	// fetched data
-	successfulTransactionsToday, err1 := dc.TransactionRepo.Find(dateFilter)
-	averageTransactionPerUser, err2 := dc.TransactionRepo.AverageTransaction()
-	latestTransactions, err3 := dc.TransactionRepo.Find("ORDER BY created_at DESC LIMIT 10")
+	ctx := c.Request.Context()
+	successfulTransactionsToday, err1 := dc.TransactionRepo.Find(ctx, successfulFilter)
+	statsPerUser, err2 := dc.TransactionRepo.Stats(ctx, createdFilter, repository.Aggregate{
+		Amount:  model.TransactionNetAmount,
+		Time:    "created_at",
+		GroupBy: []string{"user_id", "currency"},
+	})
+	latestTransactions, err3 := dc.TransactionRepo.Find(ctx, latestFilter)
 
 	// handling error
-	if err1 != nil || err2 != nil || err3 != nil {
-		util.InternalServerError(c, "internal server error", nil)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		util.Error(c, err)
 		return
 	}
 
 	// build response
 	res := dto.DashboardResponse{
+		Range:                      window.response(),
 		SuccessfulTransactionToday: buildTransactionPagination(successfulTransactionsToday),
-		AverageTransactionPerUser:  averageTransactionPerUser,
+		AverageTransactionPerUser:  buildAverageTransactions(statsPerUser),
 		LatestTransaction:          buildTransactionPagination(latestTransactions),
 	}
 
@@ -52,16 +88,69 @@ func (dc *DashboardController) GetDashboardSummary(c *gin.Context) {
 	util.Success(c, "dashboard summary fetched successfully", res)
 }
 
+func (dc *DashboardController) GetTimeseries(c *gin.Context) {
+	// bind query into struct
+	var query dto.GetTimeseriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// build date filter and buckets
+	window, err := parseDateRange(query.GetDashboardQuery, time.Now())
+	if err != nil {
+		util.Error(c, err)
+		return
+	}
+	interval, starts, err := parseInterval(query.Interval, window)
+	if err != nil {
+		util.Error(c, err)
+		return
+	}
+	bucket := repository.Bucket{
+		Field:    "created_at",
+		Interval: interval,
+		Location: window.Location,
+	}
+
+	// fetched data
+	series, err := dc.TransactionRepo.Series(c.Request.Context(), repository.Filter{Where: window.Where("created_at")}, bucket, repository.Aggregate{
+		Amount:  model.TransactionNetAmount,
+		GroupBy: []string{"status", "currency"},
+	})
+	if err != nil {
+		util.Error(c, err)
+		return
+	}
+
+	// build response
+	res := dto.TimeseriesResponse{
+		Range:    window.response(),
+		Interval: string(interval),
+		Buckets:  buildTimeseries(starts, series, window.Location),
+	}
+
+	// return response
+	util.Success(c, "dashboard timeseries fetched successfully", res)
+}
+
+func buildAverageTransactions(stats []dto.TransactionStatsAttr) []dto.AverageTransactionAttr {
+	averages := make([]dto.AverageTransactionAttr, 0, len(stats))
+	for _, s := range stats {
+		averages = append(averages, dto.AverageTransactionAttr{
+			UserId:         s.UserID,
+			Currency:       s.Currency,
+			AvgTransaction: money.New(s.Average, s.Currency),
+		})
+	}
+
+	return averages
+}
+
 func buildTransactionPagination(transactions []model.Transaction) dto.DashboardPagination[dto.TransactionResponse] {
 	mapped := make([]dto.TransactionResponse, 0, len(transactions))
 	for _, t := range transactions {
-		mapped = append(mapped, dto.TransactionResponse{
-			ID:        t.ID,
-			UserID:    t.UserID,
-			Amount:    t.Amount,
-			Status:    t.Status,
-			CreatedAt: t.CreatedAt,
-		})
+		mapped = append(mapped, dto.NewTransactionResponse(t))
 	}
 
 	return dto.DashboardPagination[dto.TransactionResponse]{
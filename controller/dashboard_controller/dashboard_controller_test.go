@@ -1,14 +1,19 @@
 package dashboardcontroller_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go-findest-rest-api/apperror"
 	dashboardcontroller "go-findest-rest-api/controller/dashboard_controller"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
 	mocks "go-findest-rest-api/mock"
 	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -23,13 +28,22 @@ func setUpRouter() *gin.Engine {
 }
 
 func TestGetTransactions(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	window := repository.All(
+		repository.Gte("created_at", time.Date(2025, 1, 1, 0, 0, 0, 0, jakarta)),
+		repository.Lt("created_at", time.Date(2025, 1, 3, 0, 0, 0, 0, jakarta)),
+	)
+
 	testCases := map[string]struct {
+		testURL               string
 		mockFindSuccessfulErr []any
 		mockAvgTransactionErr []any
 		mockFindLatestErr     []any
 		expectedStatus        int
+		expectedLatest        []dto.TransactionResponse
 	}{
 		"successfully get dashboard summary": {
+			testURL: "/api/dashboard/summary?from=2025-01-01&to=2025-01-02&tz=Asia/Jakarta",
 			mockFindSuccessfulErr: []any{[]model.Transaction{
 				{ID: 1,
 					UserID:    1,
@@ -38,10 +52,13 @@ func TestGetTransactions(t *testing.T) {
 					CreatedAt: time.Now(),
 				},
 			}, nil},
-			mockAvgTransactionErr: []any{[]dto.AverageTransactionAttr{
+			mockAvgTransactionErr: []any{[]dto.TransactionStatsAttr{
 				{
-					UserId:         1,
-					AvgTransaction: 1,
+					UserID:   1,
+					Count:    1,
+					Currency: "IDR",
+					Sum:      100,
+					Average:  100,
 				},
 			}, nil},
 			mockFindLatestErr: []any{[]model.Transaction{
@@ -49,23 +66,67 @@ func TestGetTransactions(t *testing.T) {
 					UserID:    1,
 					Amount:    1,
 					Status:    "pending",
-					CreatedAt: time.Now(),
+					CreatedAt: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+					UpdatedAt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+					Version:   model.Version{Version: 3},
 				},
 			}, nil},
 			expectedStatus: http.StatusOK,
+			expectedLatest: []dto.TransactionResponse{
+				{
+					ID:        1,
+					UserID:    1,
+					Status:    "pending",
+					CreatedAt: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+					UpdatedAt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+					Version:   3,
+				},
+			},
 		},
 		"successfully get empty dashboard summary": {
+			testURL:               "/api/dashboard/summary?from=2025-01-01&to=2025-01-02&tz=Asia/Jakarta",
 			mockFindSuccessfulErr: []any{[]model.Transaction{}, nil},
-			mockAvgTransactionErr: []any{[]dto.AverageTransactionAttr{}, nil},
+			mockAvgTransactionErr: []any{[]dto.TransactionStatsAttr{}, nil},
 			mockFindLatestErr:     []any{[]model.Transaction{}, nil},
 			expectedStatus:        http.StatusOK,
 		},
 		"error internal server error": {
+			testURL:               "/api/dashboard/summary?from=2025-01-01&to=2025-01-02&tz=Asia/Jakarta",
 			mockFindSuccessfulErr: []any{nil, errors.New("")},
 			mockAvgTransactionErr: []any{nil, errors.New("")},
 			mockFindLatestErr:     []any{nil, errors.New("")},
 			expectedStatus:        http.StatusInternalServerError,
 		},
+		"error request canceled": {
+			testURL:               "/api/dashboard/summary?from=2025-01-01&to=2025-01-02&tz=Asia/Jakarta",
+			mockFindSuccessfulErr: []any{nil, context.Canceled},
+			mockAvgTransactionErr: []any{nil, context.Canceled},
+			mockFindLatestErr:     []any{nil, context.Canceled},
+			expectedStatus:        apperror.StatusClientClosedRequest,
+		},
+		"error request timed out": {
+			testURL:               "/api/dashboard/summary?from=2025-01-01&to=2025-01-02&tz=Asia/Jakarta",
+			mockFindSuccessfulErr: []any{[]model.Transaction{}, nil},
+			mockAvgTransactionErr: []any{nil, context.DeadlineExceeded},
+			mockFindLatestErr:     []any{nil, context.DeadlineExceeded},
+			expectedStatus:        http.StatusGatewayTimeout,
+		},
+		"error invalid timezone": {
+			testURL:        "/api/dashboard/summary?tz=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error server time zone": {
+			testURL:        "/api/dashboard/summary?tz=Local",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid date": {
+			testURL:        "/api/dashboard/summary?from=01-01-2025",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error from after to": {
+			testURL:        "/api/dashboard/summary?from=2025-01-02&to=2025-01-01",
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for name, test := range testCases {
@@ -78,21 +139,186 @@ func TestGetTransactions(t *testing.T) {
 				mockUserRepo,
 			)
 
-			mockTransactionRepo.On("Find", mock.Anything).Return(test.mockFindSuccessfulErr...).Once()
-			mockTransactionRepo.On("AverageTransaction", mock.Anything).Return(test.mockAvgTransactionErr...).Once()
-			mockTransactionRepo.On("Find", mock.Anything).Return(test.mockFindLatestErr...).Once()
+			latestFilter := repository.Filter{
+				Where: window,
+				Sort:  []repository.Sort{{Field: "created_at", Descending: true}},
+				Limit: 10,
+			}
+
+			// successful transactions are those paid within the window, not those updated in it
+			successfulFilter := repository.Filter{
+				Where: repository.All(
+					repository.In("status", []string{model.StatusSuccess, model.StatusPartiallyRefunded}),
+					repository.InQuery("id", "ledger_journal_entries", "transaction_id", repository.All(
+						repository.Eq("type", ledger.JournalPayment),
+						window,
+					)),
+				),
+			}
+
+			mockTransactionRepo.On("Find", mock.Anything, latestFilter).Return(test.mockFindLatestErr...).Once()
+			mockTransactionRepo.On("Find", mock.Anything, successfulFilter).Return(test.mockFindSuccessfulErr...).Once()
+			mockTransactionRepo.On("Stats", mock.Anything, repository.Filter{Where: window}, repository.Aggregate{
+				Amount:  model.TransactionNetAmount,
+				Time:    "created_at",
+				GroupBy: []string{"user_id", "currency"},
+			}).Return(test.mockAvgTransactionErr...).Once()
 
 			router := setUpRouter()
 			router.GET("/api/dashboard/summary", controller.GetDashboardSummary)
 
 			w := httptest.NewRecorder()
 
-			req, _ := http.NewRequest(http.MethodGet, "/api/dashboard/summary", nil)
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedLatest != nil {
+				// latest transactions are listed the same way as every other transaction endpoint lists them
+				var res struct {
+					Data struct {
+						LatestTransaction struct {
+							Transactions []struct {
+								ID        uint      `json:"id"`
+								Version   uint      `json:"version"`
+								CreatedAt time.Time `json:"createdAt"`
+								UpdatedAt time.Time `json:"updatedAt"`
+							} `json:"transactions"`
+						} `json:"latestTransaction"`
+					} `json:"data"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				assert.Equal(t, len(test.expectedLatest), len(res.Data.LatestTransaction.Transactions))
+				for i, expected := range test.expectedLatest {
+					actual := res.Data.LatestTransaction.Transactions[i]
+					assert.Equal(t, expected.ID, actual.ID)
+					assert.Equal(t, expected.Version, actual.Version)
+					assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+					assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt))
+				}
+			}
+		})
+	}
+}
+
+func TestGetTimeseries(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	window := repository.All(
+		repository.Gte("created_at", time.Date(2025, 1, 1, 0, 0, 0, 0, jakarta)),
+		repository.Lt("created_at", time.Date(2025, 1, 4, 0, 0, 0, 0, jakarta)),
+	)
+	bucket := repository.Bucket{Field: "created_at", Interval: repository.IntervalDay, Location: jakarta}
+
+	testCases := map[string]struct {
+		testURL           string
+		mockSeriesErr     []any
+		expectedStatus    int
+		expectedCounts    []int64
+		expectedSuccesses []int64
+		expectedSums      []map[string]string
+	}{
+		"successfully get zero filled timeseries": {
+			testURL: "/api/dashboard/timeseries?from=2025-01-01&to=2025-01-03&tz=Asia/Jakarta&interval=day",
+			mockSeriesErr: []any{[]dto.TransactionSeriesAttr{
+				{Bucket: time.Date(2024, 12, 31, 17, 0, 0, 0, time.UTC), Status: "success", Currency: "IDR", Count: 2, Sum: 350},
+				{Bucket: time.Date(2024, 12, 31, 17, 0, 0, 0, time.UTC), Status: "failed", Currency: "IDR", Count: 1, Sum: 100},
+				{Bucket: time.Date(2025, 1, 2, 17, 0, 0, 0, time.UTC), Status: "failed", Currency: "JPY", Count: 1, Sum: 400},
+			}, nil},
+			expectedStatus:    http.StatusOK,
+			expectedCounts:    []int64{3, 0, 1},
+			expectedSuccesses: []int64{2, 0, 0},
+			expectedSums: []map[string]string{
+				{"IDR": "4.50", "JPY": "0"},
+				{"IDR": "0.00", "JPY": "0"},
+				{"IDR": "0.00", "JPY": "400"},
+			},
+		},
+		"successfully get empty timeseries": {
+			testURL:           "/api/dashboard/timeseries?from=2025-01-01&to=2025-01-03&tz=Asia/Jakarta",
+			mockSeriesErr:     []any{[]dto.TransactionSeriesAttr{}, nil},
+			expectedStatus:    http.StatusOK,
+			expectedCounts:    []int64{0, 0, 0},
+			expectedSuccesses: nil,
+			expectedSums:      []map[string]string{{}, {}, {}},
+		},
+		"error invalid interval": {
+			testURL:        "/api/dashboard/timeseries?interval=year",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error too many buckets": {
+			testURL:        "/api/dashboard/timeseries?from=2020-01-01&to=2025-01-01&interval=hour",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid timezone": {
+			testURL:        "/api/dashboard/timeseries?tz=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error server time zone": {
+			testURL:        "/api/dashboard/timeseries?tz=Local",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error internal server error": {
+			testURL:        "/api/dashboard/timeseries?from=2025-01-01&to=2025-01-03&tz=Asia/Jakarta",
+			mockSeriesErr:  []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+
+			controller := dashboardcontroller.NewDashboardController(
+				mockTransactionRepo,
+				mockUserRepo,
+			)
+
+			mockTransactionRepo.On("Series", mock.Anything, repository.Filter{Where: window}, bucket, repository.Aggregate{
+				Amount:  model.TransactionNetAmount,
+				GroupBy: []string{"status", "currency"},
+			}).Return(test.mockSeriesErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/dashboard/timeseries", controller.GetTimeseries)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
 			req.Header.Set("Content-Type", "application/json")
 
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Data struct {
+					Buckets []struct {
+						Count    int64             `json:"count"`
+						Sum      map[string]string `json:"sum"`
+						ByStatus []struct {
+							Status string `json:"status"`
+							Count  int64  `json:"count"`
+						} `json:"byStatus"`
+					} `json:"buckets"`
+				} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Len(t, body.Data.Buckets, len(test.expectedCounts))
+			for i, b := range body.Data.Buckets {
+				assert.Equal(t, test.expectedCounts[i], b.Count)
+				assert.Equal(t, test.expectedSums[i], b.Sum)
+				for _, s := range b.ByStatus {
+					if s.Status == "success" {
+						assert.Equal(t, test.expectedSuccesses[i], s.Count)
+					}
+				}
+			}
 		})
 	}
 }
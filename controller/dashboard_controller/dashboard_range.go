@@ -0,0 +1,101 @@
+package dashboardcontroller
+
+import (
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/util"
+	"time"
+)
+
+// defaultTimezone is used when no tz is given, so the window does not depend on where the server runs
+const defaultTimezone = "UTC"
+
+// dateRange is the half open window [Start, End) covering whole days in Location
+type dateRange struct {
+	Start    time.Time
+	End      time.Time
+	Location *time.Location
+}
+
+// parseDateRange reads the from and to dates, both inclusive and defaulting to today in tz
+func parseDateRange(query dto.GetDashboardQuery, now time.Time) (dateRange, error) {
+	var fields []apperror.FieldError
+
+	name := query.Timezone
+	if name == "" {
+		name = defaultTimezone
+	}
+	location, err := util.LoadTimezone(name)
+	if err != nil {
+		fields = append(fields, apperror.FieldError{
+			Field:   "tz",
+			Code:    "invalid_timezone",
+			Message: "tz must be an IANA time zone name such as Asia/Jakarta",
+		})
+		location = time.UTC
+	}
+
+	now = now.In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+	from, fromErr := parseDate("from", query.From, today, location)
+	if fromErr != nil {
+		fields = append(fields, *fromErr)
+	}
+	to, toErr := parseDate("to", query.To, from, location)
+	if toErr != nil {
+		fields = append(fields, *toErr)
+	}
+	if fromErr == nil && toErr == nil && to.Before(from) {
+		fields = append(fields, apperror.FieldError{
+			Field:   "from",
+			Code:    "invalid_range",
+			Message: "from must not be after to",
+		})
+	}
+
+	if len(fields) > 0 {
+		return dateRange{}, apperror.InvalidQuery(fields...)
+	}
+
+	// the day after to, computed on the calendar so days cut short by daylight saving stay whole
+	return dateRange{
+		Start:    from,
+		End:      time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location),
+		Location: location,
+	}, nil
+}
+
+// Where keeps the rows whose column falls inside the window
+func (r dateRange) Where(column string) repository.Expression {
+	return repository.All(
+		repository.Gte(column, r.Start),
+		repository.Lt(column, r.End),
+	)
+}
+
+func (r dateRange) response() dto.DashboardRange {
+	return dto.DashboardRange{
+		From:     r.Start,
+		To:       r.End,
+		Timezone: r.Location.String(),
+	}
+}
+
+func parseDate(name string, value string, fallback time.Time, location *time.Location) (time.Time, *apperror.FieldError) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return time.Time{}, &apperror.FieldError{
+			Field:   name,
+			Code:    "invalid_date",
+			Message: name + " must be a date formatted as YYYY-MM-DD",
+		}
+	}
+
+	return date, nil
+}
@@ -0,0 +1,175 @@
+package dashboardcontroller
+
+import (
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/repository"
+	"sort"
+	"time"
+)
+
+// maxBuckets keeps a small interval over a long range from producing an unbounded response
+const maxBuckets = 1000
+
+var intervals = map[string]repository.Interval{
+	"hour":  repository.IntervalHour,
+	"day":   repository.IntervalDay,
+	"week":  repository.IntervalWeek,
+	"month": repository.IntervalMonth,
+}
+
+// parseInterval defaults to day and rejects ranges split into more than maxBuckets buckets
+func parseInterval(value string, window dateRange) (repository.Interval, []time.Time, error) {
+	if value == "" {
+		value = string(repository.IntervalDay)
+	}
+
+	interval, ok := intervals[value]
+	if !ok {
+		return "", nil, apperror.InvalidQuery(apperror.FieldError{
+			Field:   "interval",
+			Code:    "invalid",
+			Message: "interval must be one of hour, day, week or month",
+		})
+	}
+
+	starts := bucketStarts(window, interval)
+	if len(starts) > maxBuckets {
+		return "", nil, apperror.InvalidQuery(apperror.FieldError{
+			Field:   "interval",
+			Code:    "too_many_buckets",
+			Message: "the range holds too many buckets for this interval, pick a larger interval or a shorter range",
+		})
+	}
+
+	return interval, starts, nil
+}
+
+// bucketStarts lists the start of every bucket overlapping the window, stepping on the wall clock so
+// buckets follow daylight saving the same way date_trunc does
+func bucketStarts(window dateRange, interval repository.Interval) []time.Time {
+	var starts []time.Time
+	first := truncate(window.Start, interval)
+	for i := 0; len(starts) <= maxBuckets; i++ {
+		start := advance(first, interval, i)
+		if !start.Before(window.End) {
+			break
+		}
+
+		// an hour skipped by daylight saving normalizes onto the next one
+		if len(starts) == 0 || !start.Equal(starts[len(starts)-1]) {
+			starts = append(starts, start)
+		}
+	}
+
+	return starts
+}
+
+func truncate(t time.Time, interval repository.Interval) time.Time {
+	switch interval {
+	case repository.IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case repository.IntervalWeek:
+		// monday is the first day of the week
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	case repository.IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func advance(t time.Time, interval repository.Interval, n int) time.Time {
+	switch interval {
+	case repository.IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+n, 0, 0, 0, t.Location())
+	case repository.IntervalWeek:
+		return time.Date(t.Year(), t.Month(), t.Day()+7*n, 0, 0, 0, 0, t.Location())
+	case repository.IntervalMonth:
+		return time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day()+n, 0, 0, 0, 0, t.Location())
+}
+
+// buildTimeseries places the aggregated rows into their buckets, filling the gaps with zeros. Rows are
+// matched on the wall clock of location, the same way the database truncated them
+func buildTimeseries(starts []time.Time, rows []dto.TransactionSeriesAttr, location *time.Location) []dto.TimeseriesBucket {
+	statuses := distinct(rows, func(row dto.TransactionSeriesAttr) string { return row.Status })
+	currencies := distinct(rows, func(row dto.TransactionSeriesAttr) string { return row.Currency })
+
+	buckets := make([]dto.TimeseriesBucket, 0, len(starts))
+	index := map[string]int{}
+	for _, start := range starts {
+		byStatus := make([]dto.TimeseriesStatus, 0, len(statuses))
+		for _, status := range statuses {
+			byStatus = append(byStatus, dto.TimeseriesStatus{
+				Status: status,
+				Sum:    zeroSums(currencies),
+			})
+		}
+
+		index[bucketKey(start, location)] = len(buckets)
+		buckets = append(buckets, dto.TimeseriesBucket{
+			Start:    start,
+			Sum:      zeroSums(currencies),
+			ByStatus: byStatus,
+		})
+	}
+
+	for _, row := range rows {
+		i, ok := index[bucketKey(row.Bucket, location)]
+		if !ok {
+			continue
+		}
+
+		bucket := &buckets[i]
+		bucket.Count += row.Count
+		addSum(bucket.Sum, row)
+		for j := range bucket.ByStatus {
+			if bucket.ByStatus[j].Status == row.Status {
+				bucket.ByStatus[j].Count += row.Count
+				addSum(bucket.ByStatus[j].Sum, row)
+			}
+		}
+	}
+
+	return buckets
+}
+
+// distinct returns the sorted distinct values of key over rows
+func distinct(rows []dto.TransactionSeriesAttr, key func(dto.TransactionSeriesAttr) string) []string {
+	set := map[string]bool{}
+	for _, row := range rows {
+		set[key(row)] = true
+	}
+
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+func zeroSums(currencies []string) map[string]money.Money {
+	sums := make(map[string]money.Money, len(currencies))
+	for _, currency := range currencies {
+		sums[currency] = money.New(0, currency)
+	}
+
+	return sums
+}
+
+func addSum(sums map[string]money.Money, row dto.TransactionSeriesAttr) {
+	sum := sums[row.Currency]
+	sum.Minor += row.Sum
+	sums[row.Currency] = sum
+}
+
+func bucketKey(t time.Time, location *time.Location) string {
+	return t.In(location).Format("2006-01-02T15")
+}
@@ -0,0 +1,236 @@
+package transactioncontroller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"net/http"
+)
+
+const (
+	// BulkModeAtomic creates every item or none of them, BulkModePartial creates the valid items only
+	BulkModeAtomic  = "atomic"
+	BulkModePartial = "partial"
+
+	// maxBulkItems bounds a single request, transactionservice.BatchSize bounds a single insert statement
+	maxBulkItems  = 10000
+	maxNDJSONLine = 1 << 20
+
+	bulkCreated = "created"
+	bulkFailed  = "failed"
+	bulkSkipped = "skipped"
+)
+
+var (
+	errInvalidBulkMode = apperror.InvalidQuery(apperror.FieldError{
+		Field:   "mode",
+		Code:    "invalid",
+		Message: "mode must be atomic or partial",
+	})
+	errEmptyBulk    = apperror.BadRequest("empty_bulk", "request body holds no transactions")
+	errBulkTooLarge = apperror.New(
+		http.StatusRequestEntityTooLarge,
+		"bulk_too_large",
+		fmt.Sprintf("a bulk request can hold at most %d transactions", maxBulkItems),
+	)
+)
+
+// bulkItem is one transaction of a bulk request, err is set once the item is known to fail
+type bulkItem struct {
+	payload     dto.TransactionCreate
+	transaction *model.Transaction
+	err         error
+}
+
+// CreateTransactions creates the transactions of a JSON array, or of an NDJSON body with one transaction per
+// line, and reports the outcome of every item. In atomic mode a single invalid item creates nothing, in partial
+// mode the valid items are still created
+func (tc *TransactionController) CreateTransactions(c *gin.Context) {
+	// get query from context
+	mode := c.DefaultQuery("mode", BulkModeAtomic)
+	if mode != BulkModeAtomic && mode != BulkModePartial {
+		util.Error(c, errInvalidBulkMode)
+		return
+	}
+
+	// bind payload into json
+	items, bindErr := bindBulkItems(c)
+	if bindErr != nil {
+		util.Error(c, bindErr)
+		return
+	}
+
+	// validate status and amount of every item
+	for i := range items {
+		if items[i].err == nil {
+			items[i].transaction, items[i].err = transactionservice.ValidateCreate(items[i].payload)
+		}
+	}
+
+	// check if users exist, all of them in one query
+	userIDs := make([]uint, 0, len(items))
+	seen := map[uint]bool{}
+	for _, item := range items {
+		if item.err == nil && !seen[item.payload.UserID] {
+			seen[item.payload.UserID] = true
+			userIDs = append(userIDs, item.payload.UserID)
+		}
+	}
+	existing := map[uint]bool{}
+	if len(userIDs) > 0 {
+		users, findErr := tc.UserRepo.Find(c.Request.Context(), repository.Filter{Where: repository.In("id", userIDs)})
+		if findErr != nil {
+			util.Error(c, findErr)
+			return
+		}
+		for _, user := range users {
+			existing[user.ID] = true
+		}
+	}
+
+	valid := make([]model.Transaction, 0, len(items))
+	for i := range items {
+		if items[i].err == nil && !existing[items[i].payload.UserID] {
+			items[i].err = transactionservice.ErrUserNotFound
+		}
+		if items[i].err == nil {
+			valid = append(valid, *items[i].transaction)
+		}
+	}
+
+	// nothing is created when any item failed in atomic mode, or when every item failed
+	if len(valid) == 0 || (mode == BulkModeAtomic && len(valid) < len(items)) {
+		res := buildBulkResponse(mode, items, nil)
+		util.SendResponse(c, http.StatusUnprocessableEntity, res, "no transaction created")
+		return
+	}
+
+	// insert transactions and their created events into database, posting the payments of those that succeeded
+	var created []model.Transaction
+	createErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		if err := tc.Service.LockUsers(ctx, valid); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = tc.TransactionRepo.CreateInBatches(ctx, valid, transactionservice.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		events := make([]model.TransactionEvent, 0, len(created))
+		for i := range created {
+			event, err := newEvent(c, model.EventCreated, nil, &created[i])
+			if err != nil {
+				return err
+			}
+			events = append(events, *event)
+		}
+
+		if _, err = tc.EventRepo.CreateInBatches(ctx, events, transactionservice.BatchSize); err != nil {
+			return err
+		}
+
+		return tc.Service.PostPayments(ctx, nil, created)
+	})
+	if createErr != nil {
+		util.Error(c, createErr)
+		return
+	}
+
+	// build response
+	res := buildBulkResponse(mode, items, created)
+
+	// return response
+	if res.Failed > 0 {
+		util.SendResponse(c, http.StatusMultiStatus, res, "some transaction(s) could not be created")
+		return
+	}
+	util.Created(c, "transaction(s) created successfully", res)
+}
+
+// bindBulkItems reads the items of the request body, an item that cannot be decoded fails on its own
+func bindBulkItems(c *gin.Context) ([]bulkItem, error) {
+	var raws [][]byte
+	switch c.ContentType() {
+	case "application/x-ndjson", "application/ndjson":
+		scanner := bufio.NewScanner(c.Request.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if len(raws) == maxBulkItems {
+				return nil, errBulkTooLarge
+			}
+			raws = append(raws, append([]byte(nil), line...))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, apperror.BadRequest(apperror.CodeInvalidJSON, "request body is not valid ndjson").Wrap(err)
+		}
+	default:
+		var array []json.RawMessage
+		if err := c.ShouldBindJSON(&array); err != nil {
+			return nil, apperror.FromBinding(err)
+		}
+		if len(array) > maxBulkItems {
+			return nil, errBulkTooLarge
+		}
+		for _, raw := range array {
+			raws = append(raws, raw)
+		}
+	}
+	if len(raws) == 0 {
+		return nil, errEmptyBulk
+	}
+
+	items := make([]bulkItem, len(raws))
+	for i, raw := range raws {
+		if err := json.Unmarshal(raw, &items[i].payload); err != nil {
+			items[i].err = apperror.FromBinding(err)
+		}
+	}
+
+	return items, nil
+}
+
+// buildBulkResponse pairs every item with its outcome, created holds the valid items in request order
+func buildBulkResponse(mode string, items []bulkItem, created []model.Transaction) dto.TransactionBulkResponse {
+	res := dto.TransactionBulkResponse{
+		Mode:    mode,
+		Results: make([]dto.TransactionBulkResult, 0, len(items)),
+	}
+
+	next := 0
+	for i, item := range items {
+		result := dto.TransactionBulkResult{Index: i}
+		switch {
+		case item.err != nil:
+			appErr := apperror.From(item.err)
+			result.Result = bulkFailed
+			result.Error = &dto.BulkItemError{Code: appErr.Code, Message: appErr.Message, Fields: appErr.Fields}
+			res.Failed++
+		case next < len(created):
+			transaction := dto.NewTransactionResponse(created[next])
+			result.Result = bulkCreated
+			result.Transaction = &transaction
+			res.Created++
+			next++
+		default:
+			result.Result = bulkSkipped
+		}
+		res.Results = append(res.Results, result)
+	}
+
+	return res
+}
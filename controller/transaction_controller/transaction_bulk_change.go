@@ -0,0 +1,242 @@
+package transactioncontroller
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"time"
+)
+
+var (
+	errBulkSelection = apperror.Validation(apperror.FieldError{
+		Field:   "ids",
+		Code:    "invalid",
+		Message: "either ids or filter must be given, not both",
+	})
+	errEmptyBulkFilter = apperror.Validation(apperror.FieldError{
+		Field:   "filter",
+		Code:    "required",
+		Message: "filter must have at least one condition",
+	})
+)
+
+// UpdateTransactionStatuses moves the selected transactions to one status with a single update, transactions
+// already in it or not allowed to reach it are skipped
+func (tc *TransactionController) UpdateTransactionStatuses(c *gin.Context) {
+	// bind payload into json
+	var payload dto.TransactionBulkStatus
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// validate status
+	if !model.IsValidStatus(payload.Status) {
+		util.Error(c, transactionservice.ErrInvalidStatus)
+		return
+	}
+
+	// find selected transactions
+	transactions, notFound, selectErr := tc.selectTransactions(c.Request.Context(), payload.TransactionBulkSelection)
+	if selectErr != nil {
+		util.Error(c, selectErr)
+		return
+	}
+
+	// only transactions allowed to reach the status are updated
+	var ids []uint
+	var skipped []uint
+	for _, transaction := range transactions {
+		if transaction.Status == payload.Status || !model.CanTransition(transaction.Status, payload.Status) {
+			skipped = append(skipped, transaction.ID)
+			continue
+		}
+
+		ids = append(ids, transaction.ID)
+	}
+
+	// update transactions and save their status changed events, every row is guarded by the statuses allowed to
+	// reach the new one so a transaction changed in between is skipped rather than moved against the rules
+	var updated []model.Transaction
+	if len(ids) > 0 {
+		where := repository.All(repository.In("id", ids), repository.In("status", model.SourceStatuses(payload.Status)))
+		values := map[string]interface{}{"status": payload.Status}
+
+		var updateErr error
+		updated, updateErr = tc.updateAll(c, where, values, model.EventStatusChanged, transactions)
+		if updateErr != nil {
+			util.Error(c, updateErr)
+			return
+		}
+	}
+
+	// build response
+	res := buildBulkChangeResponse(ids, updated, skipped, notFound)
+
+	// return response
+	util.Success(c, "transaction statuses updated successfully", res)
+}
+
+// DeleteTransactions soft deletes the selected transactions with a single update
+func (tc *TransactionController) DeleteTransactions(c *gin.Context) {
+	// bind payload into json
+	var payload dto.TransactionBulkSelection
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// find selected transactions
+	transactions, notFound, selectErr := tc.selectTransactions(c.Request.Context(), payload)
+	if selectErr != nil {
+		util.Error(c, selectErr)
+		return
+	}
+
+	// delete transactions and save their deleted events
+	ids := make([]uint, 0, len(transactions))
+	for _, transaction := range transactions {
+		ids = append(ids, transaction.ID)
+	}
+
+	var updated []model.Transaction
+	if len(ids) > 0 {
+		deleted := model.Deleted(time.Now())
+		values := map[string]interface{}{"is_deleted": deleted.IsDeleted, "deleted_at": deleted.DeletedAt}
+
+		var updateErr error
+		updated, updateErr = tc.updateAll(c, repository.In("id", ids), values, model.EventDeleted, transactions)
+		if updateErr != nil {
+			util.Error(c, updateErr)
+			return
+		}
+	}
+
+	// build response
+	res := buildBulkChangeResponse(ids, updated, nil, notFound)
+
+	// return response
+	util.Success(c, "transaction(s) deleted successfully", res)
+}
+
+// selectTransactions finds the live transactions named by the ids or the filter of selection, along with the
+// ids that were not found
+func (tc *TransactionController) selectTransactions(ctx context.Context, selection dto.TransactionBulkSelection) ([]model.Transaction, []uint, error) {
+	if (len(selection.IDs) > 0) == (selection.Filter != nil) {
+		return nil, nil, errBulkSelection
+	}
+
+	var where repository.Expression
+	if selection.Filter != nil {
+		var err error
+		where, _, err = buildTransactionFilter(*selection.Filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		if group, ok := where.(repository.Group); ok && len(group.Expressions) == 0 {
+			return nil, nil, errEmptyBulkFilter
+		}
+
+		// a filter must not reach more transactions than a list of ids could
+		total, err := tc.TransactionRepo.Count(ctx, repository.Filter{Where: where})
+		if err != nil {
+			return nil, nil, err
+		}
+		if total > maxBulkItems {
+			return nil, nil, errBulkTooLarge
+		}
+	} else {
+		if len(selection.IDs) > maxBulkItems {
+			return nil, nil, errBulkTooLarge
+		}
+		where = repository.In("id", selection.IDs)
+	}
+
+	transactions, err := tc.TransactionRepo.Find(ctx, repository.Filter{Where: where})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[uint]bool, len(transactions))
+	for _, transaction := range transactions {
+		seen[transaction.ID] = true
+	}
+	var notFound []uint
+	for _, id := range selection.IDs {
+		if !seen[id] {
+			seen[id] = true
+			notFound = append(notFound, id)
+		}
+	}
+
+	return transactions, notFound, nil
+}
+
+// updateAll sets values on the transactions matching where and records an event for each updated one, along with
+// the payment of those that reached success. before holds the transactions as they were read
+func (tc *TransactionController) updateAll(c *gin.Context, where repository.Expression, values map[string]interface{}, eventType string, before []model.Transaction) ([]model.Transaction, error) {
+	previous := make(map[uint]*model.Transaction, len(before))
+	for i := range before {
+		previous[before[i].ID] = &before[i]
+	}
+
+	var updated []model.Transaction
+	err := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		var err error
+		updated, err = tc.TransactionRepo.UpdateAll(ctx, repository.Filter{Where: where}, values)
+		if err != nil {
+			return err
+		}
+
+		events := make([]model.TransactionEvent, 0, len(updated))
+		for i := range updated {
+			event, err := newEvent(c, eventType, previous[updated[i].ID], &updated[i])
+			if err != nil {
+				return err
+			}
+			events = append(events, *event)
+		}
+
+		if _, err = tc.EventRepo.CreateInBatches(ctx, events, transactionservice.BatchSize); err != nil {
+			return err
+		}
+
+		return tc.Service.PostPayments(ctx, before, updated)
+	})
+
+	return updated, err
+}
+
+// buildBulkChangeResponse counts the outcome of a bulk change, ids that were meant to change but were not
+// updated changed in between and are skipped as well
+func buildBulkChangeResponse(ids []uint, updated []model.Transaction, skipped []uint, notFound []uint) dto.TransactionBulkChangeResponse {
+	changed := make(map[uint]bool, len(updated))
+	for _, transaction := range updated {
+		changed[transaction.ID] = true
+	}
+	for _, id := range ids {
+		if !changed[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	if skipped == nil {
+		skipped = []uint{}
+	}
+	if notFound == nil {
+		notFound = []uint{}
+	}
+
+	return dto.TransactionBulkChangeResponse{
+		Affected:    len(updated),
+		Skipped:     len(skipped),
+		NotFound:    len(notFound),
+		SkippedIDs:  skipped,
+		NotFoundIDs: notFound,
+	}
+}
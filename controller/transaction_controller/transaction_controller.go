@@ -1,31 +1,62 @@
 package transactioncontroller
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
 	"go-findest-rest-api/model"
+	"go-findest-rest-api/pagination"
 	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
 	"go-findest-rest-api/util"
 	"gorm.io/gorm"
-	"strconv"
+	"net/http"
 	"strings"
 	"time"
 )
 
+var (
+	errTransactionNotFound = apperror.NotFound("transaction_not_found", "transaction not found or already deleted")
+	errPreconditionFailed  = apperror.New(http.StatusPreconditionFailed, "precondition_failed", "transaction does not match If-Match, fetch it again")
+	errVersionConflict     = apperror.Conflict("version_conflict", "transaction was changed by another request, fetch it again")
+)
+
 type TransactionController struct {
 	TransactionRepo repository.DatabaseRepository[model.Transaction]
 	UserRepo        repository.DatabaseRepository[model.User]
+	EventRepo       repository.DatabaseRepository[model.TransactionEvent]
+	RefundRepo      repository.DatabaseRepository[model.Refund]
+	Transactor      repository.Transactor
+	// Ledger gets a journal for every transaction reaching success and every refund
+	Ledger ledger.Ledger
+	// DeletedRetention is how long deleted transactions are kept before a purge removes them for good
+	DeletedRetention time.Duration
+	// Service applies the rules shared with the command line, over the same repositories and ledger
+	Service *transactionservice.TransactionService
 }
 
 func NewTransactionController(
 	transactionRepo repository.DatabaseRepository[model.Transaction],
 	userRepo repository.DatabaseRepository[model.User],
+	eventRepo repository.DatabaseRepository[model.TransactionEvent],
+	refundRepo repository.DatabaseRepository[model.Refund],
+	transactor repository.Transactor,
+	ledger ledger.Ledger,
+	deletedRetention time.Duration,
 ) *TransactionController {
 	return &TransactionController{
-		TransactionRepo: transactionRepo,
-		UserRepo:        userRepo,
+		TransactionRepo:  transactionRepo,
+		UserRepo:         userRepo,
+		EventRepo:        eventRepo,
+		RefundRepo:       refundRepo,
+		Transactor:       transactor,
+		Ledger:           ledger,
+		DeletedRetention: deletedRetention,
+		Service:          transactionservice.NewTransactionService(transactionRepo, userRepo, eventRepo, transactor, ledger),
 	}
 }
 
@@ -33,112 +64,138 @@ func (tc *TransactionController) CreateTransaction(c *gin.Context) {
 	// bind payload into json
 	var payload dto.TransactionCreate
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		util.InternalServerError(c, err.Error(), nil)
+		util.Error(c, apperror.FromBinding(err))
 		return
 	}
 
-	// validate status
-	if !isValidStatus(payload.Status) {
-		util.InternalServerError(c, "status must be success, pending, or failed", nil)
+	// validate status and amount
+	newTransaction, validateErr := transactionservice.ValidateCreate(payload)
+	if validateErr != nil {
+		util.Error(c, validateErr)
 		return
 	}
 
-	// check if user exist
-	_, firstErr := tc.UserRepo.First(payload.UserID)
-	if firstErr != nil {
-		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
-			util.NotFound(c, "user not found", nil)
-			return
+	// insert transaction and its created event into database, posting its payment when it succeeded already
+	var transaction *model.Transaction
+	createErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		// check if user exist, keeping it from being deleted until the transaction is committed
+		if _, err := tc.UserRepo.First(repository.ForKeyShare(ctx), payload.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return transactionservice.ErrUserNotFound
+			}
+
+			return err
 		}
 
-		util.InternalServerError(c, firstErr.Error(), nil)
-		return
-	}
+		var err error
+		transaction, err = tc.TransactionRepo.Create(ctx, newTransaction)
+		if err != nil {
+			return err
+		}
+
+		if err := tc.recordEvent(c, ctx, model.EventCreated, nil, transaction); err != nil {
+			return err
+		}
 
-	// insert transaction into database
-	transaction, createErr := tc.TransactionRepo.Create(
-		&model.Transaction{
-			UserID: payload.UserID,
-			Amount: payload.Amount,
-			Status: payload.Status,
-		},
-	)
+		return tc.Service.PostPayments(ctx, nil, []model.Transaction{*transaction})
+	})
 	if createErr != nil {
-		util.InternalServerError(c, createErr.Error(), nil)
+		util.Error(c, createErr)
 		return
 	}
 
 	// build response
-	res := dto.TransactionResponse{
-		ID:        transaction.ID,
-		UserID:    transaction.UserID,
-		Amount:    transaction.Amount,
-		Status:    transaction.Status,
-		CreatedAt: transaction.CreatedAt,
-	}
+	c.Header("ETag", util.ETag(transaction.Version.Version))
+	res := dto.NewTransactionResponse(*transaction)
 
 	// return response
 	util.Created(c, "transaction created successfully", res)
 }
 
 func (tc *TransactionController) GetTransactions(c *gin.Context) {
+	tc.listTransactions(c, nil)
+}
+
+func (tc *TransactionController) GetUserTransactions(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if user exist
+	_, firstErr := tc.UserRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, transactionservice.ErrUserNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	tc.listTransactions(c, repository.Eq("user_id", id))
+}
+
+// listTransactions responds with a page of the transactions matching the query string, narrowed down by scope
+func (tc *TransactionController) listTransactions(c *gin.Context, scope repository.Expression) {
 	// bind payload into json
 	var payload dto.GetTransactionsQuery
 	if err := c.ShouldBindQuery(&payload); err != nil {
-		util.InternalServerError(c, err.Error(), nil)
+		util.Error(c, apperror.FromBinding(err))
 		return
 	}
+	payload.Metadata = metadataQuery(c)
 
 	// map payload into filters
-	var filters = map[string]interface{}{}
-	if payload.UserID != 0 {
-		filters["user_id"] = payload.UserID
-	}
-	if payload.Status != "" {
-		filters["status"] = payload.Status
-	}
-
-	// build filter string
-	var conditions []string
-	for k, v := range filters {
-		var valueStr string
-		switch v := v.(type) {
-		case string:
-			valueStr = fmt.Sprintf("'%s'", v)
-		case uint:
-			valueStr = strconv.FormatUint(uint64(v), 10)
-		}
+	where, sort, filterErr := buildTransactionFilter(payload)
+	if filterErr != nil {
+		util.Error(c, filterErr)
+		return
+	}
+	if scope != nil {
+		where = repository.All(scope, where)
+	}
 
-		conditions = append(conditions, fmt.Sprintf("%s = %s", k, valueStr))
+	// resolve requested page
+	page, pageErr := pagination.NewPage(payload.Page, payload.PageSize, payload.After, payload.Before)
+	if pageErr == nil {
+		page, pageErr = page.Sorted(sort)
+	}
+	if pageErr != nil {
+		util.Error(c, pageErr)
+		return
 	}
 
-	// join conditions with " OR "
-	var filter string
-	if conditions != nil && len(conditions) > 0 {
-		filter = "AND " + strings.Join(conditions, " OR ")
+	// count all matching transactions
+	total, countErr := tc.TransactionRepo.Count(c.Request.Context(), repository.Filter{Where: where})
+	if countErr != nil {
+		util.Error(c, countErr)
+		return
 	}
 
-	// find all transactions
-	transactions, findErr := tc.TransactionRepo.Find(filter)
+	// find transactions of the page
+	transactions, findErr := tc.TransactionRepo.Find(c.Request.Context(), page.Filter(where))
 	if findErr != nil {
-		util.NotFound(c, "transactions not found", []dto.TransactionResponse{})
+		util.Error(c, findErr)
 		return
 	}
+	transactions, nextCursor, prevCursor := pagination.Rows(page, transactions, transactionCursor)
 
 	// build response
 	res := dto.Pagination[dto.TransactionResponse]{
-		TotalRecords: len(transactions),
+		TotalRecords: int(total),
+		Page:         page.Number,
+		PageSize:     page.Size,
+		NextCursor:   nextCursor,
+		PrevCursor:   prevCursor,
 		Data:         []dto.TransactionResponse{},
 	}
 	if len(transactions) > 0 {
 		for _, transaction := range transactions {
-			res.Data = append(res.Data, dto.TransactionResponse{
-				ID:        transaction.ID,
-				UserID:    transaction.UserID,
-				Amount:    transaction.Amount,
-				Status:    transaction.Status,
-				CreatedAt: transaction.CreatedAt,
-			})
+			res.Data = append(res.Data, dto.NewTransactionResponse(transaction))
 		}
 	}
 
@@ -148,124 +205,213 @@ func (tc *TransactionController) GetTransactions(c *gin.Context) {
 
 func (tc *TransactionController) GetTransactionById(c *gin.Context) {
 	// get param from context
-	id := c.Param("id")
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
 
 	// check if transaction exist
-	transaction, firstErr := tc.TransactionRepo.First(id)
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
 	if firstErr != nil {
 		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
-			util.NotFound(c, "transaction not found or already deleted", nil)
+			util.Error(c, errTransactionNotFound)
 			return
 		}
 
-		util.InternalServerError(c, firstErr.Error(), nil)
+		util.Error(c, firstErr)
 		return
 	}
 
-	// build response
-	res := dto.TransactionResponse{
-		ID:        transaction.ID,
-		UserID:    transaction.UserID,
-		Amount:    transaction.Amount,
-		Status:    transaction.Status,
-		CreatedAt: transaction.CreatedAt,
+	// tag the version, a client already holding it gets no body
+	etag := util.ETag(transaction.Version.Version)
+	c.Header("ETag", etag)
+	if util.IfNoneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
 	}
 
+	// build response
+	res := dto.NewTransactionResponse(*transaction)
+
 	// return response
 	util.Success(c, "transaction fetched successfully", res)
 }
 
 func (tc *TransactionController) UpdateTransaction(c *gin.Context) {
 	// get param from context
-	id := c.Param("id")
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
 
 	// bind payload into json
 	var payload dto.TransactionUpdate
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		util.InternalServerError(c, err.Error(), nil)
+		util.Error(c, apperror.FromBinding(err))
 		return
 	}
 
 	// validate status
-	if !isValidStatus(payload.Status) {
-		util.InternalServerError(c, "status must be success, pending, or failed", nil)
+	if !model.IsValidStatus(payload.Status) {
+		util.Error(c, transactionservice.ErrInvalidStatus)
 		return
 	}
 
 	// check if transaction exist
-	transaction, firstErr := tc.TransactionRepo.First(id)
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
 	if firstErr != nil {
 		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
-			util.NotFound(c, "transaction not found or already deleted", nil)
+			util.Error(c, errTransactionNotFound)
 			return
 		}
 
-		util.InternalServerError(c, firstErr.Error(), nil)
+		util.Error(c, firstErr)
+		return
+	}
+
+	// check if the client changes the version it read
+	if !util.IfMatch(c, util.ETag(transaction.Version.Version)) {
+		util.Error(c, errPreconditionFailed)
+		return
+	}
+
+	// setting the current status again changes nothing
+	if transaction.Status == payload.Status {
+		c.Header("ETag", util.ETag(transaction.Version.Version))
+		util.Success(c, "transaction status updated successfully", dto.NewTransactionResponse(*transaction))
 		return
 	}
 
-	// update transaction and save it to database
-	updatedTransaction, saveErr := tc.TransactionRepo.Save(
-		&model.Transaction{
-			ID:        transaction.ID,
-			UserID:    transaction.UserID,
-			Amount:    transaction.Amount,
-			Status:    payload.Status,
-			CreatedAt: transaction.CreatedAt,
-			UpdatedAt: time.Now(),
-		},
-		id,
-	)
+	// check if the status may change
+	if !model.CanTransition(transaction.Status, payload.Status) {
+		util.Error(c, illegalTransition(transaction.Status, payload.Status))
+		return
+	}
+
+	// update transaction and save it to database along with its status changed event and, on success, its payment journal
+	updated := *transaction
+	updated.Status = payload.Status
+	updated.UpdatedAt = time.Now()
+
+	var updatedTransaction *model.Transaction
+	saveErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		var err error
+		updatedTransaction, err = tc.TransactionRepo.Save(ctx, &updated, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tc.recordEvent(c, ctx, model.EventStatusChanged, transaction, updatedTransaction); err != nil {
+			return err
+		}
+
+		return tc.Service.PostPayments(ctx, []model.Transaction{*transaction}, []model.Transaction{*updatedTransaction})
+	})
 	if saveErr != nil {
-		util.InternalServerError(c, saveErr.Error(), nil)
+		util.Error(c, saveConflict(c, saveErr))
 		return
 	}
 
 	// build response
-	res := dto.TransactionResponse{
-		ID:        transaction.ID,
-		UserID:    transaction.UserID,
-		Amount:    transaction.Amount,
-		Status:    updatedTransaction.Status,
-		CreatedAt: transaction.CreatedAt,
-		UpdatedAt: updatedTransaction.UpdatedAt,
-	}
+	c.Header("ETag", util.ETag(updatedTransaction.Version.Version))
+	res := dto.NewTransactionResponse(*updatedTransaction)
 
 	// return response
 	util.Success(c, "transaction status updated successfully", res)
 }
 
+func (tc *TransactionController) GetTransactionStatuses(c *gin.Context) {
+	// build response
+	rules := model.StatusRules()
+	res := make([]dto.TransactionStatusResponse, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, dto.TransactionStatusResponse{
+			Status:      rule.Status,
+			Initial:     rule.Initial,
+			Final:       len(rule.Transitions) == 0 && !rule.Refundable,
+			Transitions: rule.Transitions,
+			Refundable:  rule.Refundable,
+		})
+	}
+
+	// return response
+	util.Success(c, "transaction statuses fetched successfully", res)
+}
+
+func (tc *TransactionController) GetTransactionTransitions(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if transaction exist
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errTransactionNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// build response
+	res := dto.TransactionTransitionsResponse{
+		ID:          transaction.ID,
+		Status:      transaction.Status,
+		Transitions: model.Transitions(transaction.Status),
+		Refundable:  model.IsRefundable(transaction.Status),
+	}
+
+	// return response
+	util.Success(c, "transaction transitions fetched successfully", res)
+}
+
 func (tc *TransactionController) DeleteTransaction(c *gin.Context) {
 	// get param from context
-	id := c.Param("id")
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
 
 	// check if transaction exist
-	transaction, firstErr := tc.TransactionRepo.First(id)
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
 	if firstErr != nil {
 		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
-			util.NotFound(c, "transaction not found or already deleted", nil)
+			util.Error(c, errTransactionNotFound)
 			return
 		}
 
-		util.InternalServerError(c, firstErr.Error(), nil)
+		util.Error(c, firstErr)
 		return
 	}
 
-	// delete transaction and save it to database
-	_, saveErr := tc.TransactionRepo.Save(
-		&model.Transaction{
-			ID:        transaction.ID,
-			UserID:    transaction.UserID,
-			Amount:    transaction.Amount,
-			Status:    transaction.Status,
-			IsDeleted: true,
-			CreatedAt: transaction.CreatedAt,
-			UpdatedAt: transaction.UpdatedAt,
-		},
-		id,
-	)
+	// check if the client deletes the version it read
+	if !util.IfMatch(c, util.ETag(transaction.Version.Version)) {
+		util.Error(c, errPreconditionFailed)
+		return
+	}
+
+	// delete transaction and save it to database along with its deleted event
+	deleted := *transaction
+	deleted.SoftDelete = model.Deleted(time.Now())
+
+	saveErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		deletedTransaction, err := tc.TransactionRepo.Save(ctx, &deleted, id)
+		if err != nil {
+			return err
+		}
+
+		return tc.recordEvent(c, ctx, model.EventDeleted, transaction, deletedTransaction)
+	})
 	if saveErr != nil {
-		util.InternalServerError(c, saveErr.Error(), nil)
+		util.Error(c, saveConflict(c, saveErr))
 		return
 	}
 
@@ -273,12 +419,35 @@ func (tc *TransactionController) DeleteTransaction(c *gin.Context) {
 	util.Success(c, "transaction deleted successfully", nil)
 }
 
-func isValidStatus(status string) bool {
-	validStatuses := map[string]bool{
-		"success": true,
-		"pending": true,
-		"failed":  true,
+func transactionCursor(t model.Transaction) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: t.CreatedAt, ID: t.ID}
+}
+
+// saveConflict reports a transaction saved by another request since it was read, as a failed precondition
+// when the client sent If-Match
+func saveConflict(c *gin.Context, err error) error {
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+	if c.GetHeader("If-Match") != "" {
+		return errPreconditionFailed.Wrap(err)
+	}
+
+	return errVersionConflict.Wrap(err)
+}
+
+func illegalTransition(from string, to string) *apperror.Error {
+	allowed := model.Transitions(from)
+	message := fmt.Sprintf("status cannot change from %s to %s, %s is final", from, to, from)
+	switch {
+	case model.IsRefundedStatus(to):
+		message = fmt.Sprintf("status cannot change to %s, it is set by refunding the transaction", to)
+	case len(allowed) > 0:
+		message = fmt.Sprintf("status cannot change from %s to %s, allowed: %s", from, to, strings.Join(allowed, ", "))
+	case model.IsRefundable(from):
+		message = fmt.Sprintf("status cannot change from %s to %s, %s can only be refunded", from, to, from)
 	}
 
-	return validStatuses[status]
+	return apperror.Conflict("invalid_transition", message)
 }
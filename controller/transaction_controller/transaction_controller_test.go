@@ -2,18 +2,29 @@ package transactioncontroller_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/stretchr/testify/mock"
+	"go-findest-rest-api/apperror"
 	"go-findest-rest-api/controller/transaction_controller"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/idempotency"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/middleware"
 	mocks "go-findest-rest-api/mock"
 	"go-findest-rest-api/model"
+	"go-findest-rest-api/pagination"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/util"
 	"gorm.io/gorm"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,16 +38,19 @@ func setUpRouter() *gin.Engine {
 
 func TestCreateTransaction(t *testing.T) {
 	testCases := map[string]struct {
-		mockBody       any
-		mockFirstErr   []any
-		mockCreateErr  []any
-		expectedStatus int
+		mockBody        any
+		mockFirstErr    []any
+		mockCreateErr   []any
+		mockEventErr    []any
+		expectedCreated *model.Transaction
+		expectedStatus  int
 	}{
 		"successfully created transaction": {
 			mockBody: &dto.TransactionCreate{
-				UserID: 1,
-				Amount: 1,
-				Status: "pending",
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
 			},
 			mockFirstErr: []any{&model.User{ID: 1}, nil},
 			mockCreateErr: []any{&model.Transaction{
@@ -44,62 +58,188 @@ func TestCreateTransaction(t *testing.T) {
 				Amount: 1,
 				Status: "pending",
 			}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusCreated,
+		},
+		"successfully created transaction with description, metadata and tags": {
+			mockBody: map[string]any{
+				"userId":      1,
+				"amount":      "1",
+				"currency":    "IDR",
+				"status":      "pending",
+				"description": "  Order #123 ",
+				"metadata":    map[string]any{"orderId": json.Number("12345678901234567890"), "channel": "web", "gift": true},
+				"tags":        []string{"Promo", "promo", " vip "},
+			},
+			mockFirstErr:  []any{&model.User{ID: 1}, nil},
+			mockCreateErr: []any{&model.Transaction{ID: 1, UserID: 1, Amount: 1, Status: "pending"}, nil},
+			mockEventErr:  []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedCreated: &model.Transaction{
+				Description: "Order #123",
+				Metadata:    model.Metadata{"orderId": json.Number("12345678901234567890"), "channel": "web", "gift": true},
+				Tags:        model.Tags{"promo", "vip"},
+			},
 			expectedStatus: http.StatusCreated,
 		},
+		"error metadata values must be flat": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "metadata": map[string]any{"order": map[string]any{"id": 1}}},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error metadata must be an object": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "metadata": []string{"orderId"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error metadata key is invalid": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "metadata": map[string]any{"order id": "1"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error metadata is too large": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "metadata": map[string]any{"note": strings.Repeat("a", 9000)}},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error tag is invalid": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "tags": []string{"promo,vip"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error too many tags": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "tags": strings.Split("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u", ",")},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error description is too long": {
+			mockBody:       map[string]any{"userId": 1, "amount": "1", "currency": "IDR", "status": "pending", "description": strings.Repeat("a", 1001)},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 		"error cannot bind payload into json": {
 			mockBody:       "wrong-format",
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusBadRequest,
 		},
 		"error status must be success, pending, or failed": {
 			mockBody: &dto.TransactionCreate{
-				UserID: 1,
-				Amount: 1,
-				Status: "qwer",
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "qwer",
 			},
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusUnprocessableEntity,
 		},
-		"error user not found": {
+		"error status cannot be created as refunded": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "refunded",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error unknown currency": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "XYZ",
+				Status:   "pending",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error missing currency": {
 			mockBody: &dto.TransactionCreate{
 				UserID: 1,
-				Amount: 1,
+				Amount: "1",
 				Status: "pending",
 			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount more precise than currency": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1.5",
+				Currency: "JPY",
+				Status:   "pending",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount not positive": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "0",
+				Currency: "IDR",
+				Status:   "pending",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount is not a number": {
+			mockBody:       map[string]any{"userId": 1, "amount": "ten", "currency": "IDR", "status": "pending"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user not found": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
+			},
 			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
 			expectedStatus: http.StatusNotFound,
 		},
 		"error user internal server error": {
 			mockBody: &dto.TransactionCreate{
-				UserID: 1,
-				Amount: 1,
-				Status: "pending",
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
 			},
 			mockFirstErr:   []any{(*model.User)(nil), errors.New("")},
 			expectedStatus: http.StatusInternalServerError,
 		},
 		"error cannot insert transaction into database": {
 			mockBody: &dto.TransactionCreate{
-				UserID: 1,
-				Amount: 1,
-				Status: "pending",
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
 			},
 			mockFirstErr:   []any{&model.User{ID: 1}, nil},
 			mockCreateErr:  []any{(*model.Transaction)(nil), errors.New("")},
 			expectedStatus: http.StatusInternalServerError,
 		},
+		"error cannot record created event": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
+			},
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockCreateErr:  []any{&model.Transaction{ID: 1, UserID: 1, Amount: 1, Currency: "IDR", Status: "pending"}, nil},
+			mockEventErr:   []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
 	}
 
 	for name, test := range testCases {
 		t.Run(name, func(t *testing.T) {
 			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
 			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := transactioncontroller.NewTransactionController(
 				mockTransactionRepo,
 				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
 			)
 
 			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
-			mockTransactionRepo.On("Create", mock.Anything).Return(test.mockCreateErr...).Once()
+			mockTransactionRepo.On("Create", mock.Anything, mock.Anything).Return(test.mockCreateErr...).Once()
+
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == model.EventCreated && event.Actor == "anonymous"
+			})).Return(test.mockEventErr...)
 
 			router := setUpRouter()
 			router.POST("/api/transactions", controller.CreateTransaction)
@@ -113,54 +253,85 @@ func TestCreateTransaction(t *testing.T) {
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedCreated != nil {
+				mockTransactionRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					return transaction.Description == test.expectedCreated.Description &&
+						reflect.DeepEqual(transaction.Metadata, test.expectedCreated.Metadata) &&
+						reflect.DeepEqual(transaction.Tags, test.expectedCreated.Tags)
+				}))
+			}
 		})
 	}
 }
 
-func TestGetTransactions(t *testing.T) {
+func TestCreateTransactionIdempotency(t *testing.T) {
+	type step struct {
+		key              string
+		body             string
+		expectedStatus   int
+		expectedReplayed bool
+	}
+
+	payload := `{"userId":1,"amount":"1","currency":"IDR","status":"pending"}`
+	created := []any{&model.Transaction{ID: 1, UserID: 1, Amount: 100, Currency: "IDR", Status: "pending", Version: model.Version{Version: 1}}, nil}
+
 	testCases := map[string]struct {
-		testURL        string
-		mockFindErr    []any
-		expectedStatus int
+		ttl             time.Duration
+		mockCreateErrs  [][]any
+		steps           []step
+		expectedCreates int
 	}{
-		"successfully get transaction with query": {
-			testURL: "/api/transactions?userId=1&status=pending",
-			mockFindErr: []any{[]model.Transaction{
-				{ID: 1,
-					UserID:    1,
-					Amount:    1,
-					Status:    "pending",
-					CreatedAt: time.Now(),
-				},
-			}, nil},
-			expectedStatus: http.StatusOK,
+		"successfully replayed response of retried request": {
+			ttl:            time.Hour,
+			mockCreateErrs: [][]any{created},
+			steps: []step{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated, expectedReplayed: true},
+				{key: "key-1", body: "{\n  \"userId\": 1, \"amount\": \"1\", \"currency\": \"IDR\", \"status\": \"pending\"\n}", expectedStatus: http.StatusCreated, expectedReplayed: true},
+			},
+			expectedCreates: 1,
 		},
-		"successfully get transaction without query": {
-			testURL: "/api/transactions",
-			mockFindErr: []any{[]model.Transaction{
-				{ID: 1,
-					UserID:    1,
-					Amount:    1,
-					Status:    "pending",
-					CreatedAt: time.Now(),
-				},
-				{ID: 2,
-					UserID:    2,
-					Amount:    2,
-					Status:    "success",
-					CreatedAt: time.Now(),
-				},
-			}, nil},
-			expectedStatus: http.StatusOK,
+		"successfully created again with another key": {
+			ttl:            time.Hour,
+			mockCreateErrs: [][]any{created, created},
+			steps: []step{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-2", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCreates: 2,
 		},
-		"error get transactions": {
-			testURL:        "/api/transactions",
-			mockFindErr:    []any{nil, errors.New("")},
-			expectedStatus: http.StatusNotFound,
+		"successfully created again once the key expired": {
+			ttl:            time.Nanosecond,
+			mockCreateErrs: [][]any{created, created},
+			steps: []step{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCreates: 2,
 		},
-		"error cannot bind payload into json": {
-			testURL:        "/api/transactions?userId=wrong-format",
-			expectedStatus: http.StatusInternalServerError,
+		"successfully retried request that failed with a server error": {
+			ttl:            time.Hour,
+			mockCreateErrs: [][]any{{(*model.Transaction)(nil), errors.New("")}, created},
+			steps: []step{
+				{key: "key-1", body: payload, expectedStatus: http.StatusInternalServerError},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCreates: 2,
+		},
+		"error key reused with a different payload": {
+			ttl:            time.Hour,
+			mockCreateErrs: [][]any{created},
+			steps: []step{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: `{"userId":1,"amount":"2","currency":"IDR","status":"pending"}`, expectedStatus: http.StatusUnprocessableEntity},
+			},
+			expectedCreates: 1,
+		},
+		"error key too long": {
+			ttl: time.Hour,
+			steps: []step{
+				{key: strings.Repeat("k", 256), body: payload, expectedStatus: http.StatusBadRequest},
+			},
 		},
 	}
 
@@ -168,49 +339,184 @@ func TestGetTransactions(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
 			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := transactioncontroller.NewTransactionController(
 				mockTransactionRepo,
 				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
 			)
 
-			mockTransactionRepo.On("Find", mock.Anything).Return(test.mockFindErr...).Once()
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(&model.User{ID: 1}, nil)
+			for _, mockCreateErr := range test.mockCreateErrs {
+				mockTransactionRepo.On("Create", mock.Anything, mock.Anything).Return(mockCreateErr...).Once()
+			}
+			mockEventRepo.On("Create", mock.Anything, mock.Anything).Return(&model.TransactionEvent{ID: 1}, nil)
 
 			router := setUpRouter()
-			router.GET("/api/transactions", controller.GetTransactions)
+			router.POST("/api/transactions", idempotency.Middleware(idempotency.NewMemoryStore(), test.ttl), controller.CreateTransaction)
 
-			w := httptest.NewRecorder()
+			for _, step := range test.steps {
+				w := httptest.NewRecorder()
 
-			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
-			req.Header.Set("Content-Type", "application/json")
+				req, _ := http.NewRequest(http.MethodPost, "/api/transactions", strings.NewReader(step.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(idempotency.KeyHeader, step.key)
 
-			router.ServeHTTP(w, req)
+				router.ServeHTTP(w, req)
 
-			assert.Equal(t, test.expectedStatus, w.Code)
+				assert.Equal(t, step.expectedStatus, w.Code)
+				assert.Equal(t, step.expectedReplayed, w.Header().Get(idempotency.ReplayedHeader) == "true")
+				if step.expectedStatus == http.StatusCreated {
+					// a replayed response keeps the entity tag of the created transaction
+					assert.Equal(t, util.ETag(1), w.Header().Get("ETag"))
+				}
+			}
+
+			mockTransactionRepo.AssertNumberOfCalls(t, "Create", test.expectedCreates)
 		})
 	}
 }
 
-func TestGetTransactionById(t *testing.T) {
+func TestCreateTransactions(t *testing.T) {
+	twoItems := `[{"userId": 1, "amount": "1500", "currency": "IDR", "status": "pending"}, {"userId": 2, "amount": 12.5, "currency": "USD", "status": "success"}]`
+	created := []model.Transaction{
+		{ID: 1, UserID: 1, Amount: 150000, Currency: "IDR", Status: "pending"},
+		{ID: 2, UserID: 2, Amount: 1250, Currency: "USD", Status: "success"},
+	}
+
 	testCases := map[string]struct {
-		testURL        string
-		mockFirstErr   []any
-		expectedStatus int
+		testURL         string
+		contentType     string
+		mockBody        string
+		expectedUserIDs []uint
+		mockFindErr     []any
+		mockLockErr     []any
+		mockCreateErr   []any
+		mockEventErr    []any
+		expectedStatus  int
+		expectedCreated int
+		expectedFailed  int
+		expectedResults []string
 	}{
-		"successfully get transaction by id": {
-			testURL:        "/api/transactions/1",
-			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
-			expectedStatus: http.StatusOK,
+		"successfully created transactions from a json array": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        twoItems,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockCreateErr:   []any{created, nil},
+			mockEventErr:    []any{[]model.TransactionEvent{{ID: 1}, {ID: 2}}, nil},
+			expectedStatus:  http.StatusCreated,
+			expectedCreated: 2,
+			expectedResults: []string{"created", "created"},
 		},
-		"error transaction not found": {
-			testURL:        "/api/transactions/10",
-			mockFirstErr:   []any{nil, gorm.ErrRecordNotFound},
-			expectedStatus: http.StatusNotFound,
+		"successfully created transactions from ndjson": {
+			testURL:     "/api/transactions/bulk",
+			contentType: "application/x-ndjson",
+			mockBody: `{"userId": 1, "amount": "1500", "currency": "IDR", "status": "pending"}
+
+{"userId": 1, "amount": 12.5, "currency": "USD", "status": "success"}
+`,
+			expectedUserIDs: []uint{1},
+			mockFindErr:     []any{[]model.User{{ID: 1}}, nil},
+			mockCreateErr:   []any{created, nil},
+			mockEventErr:    []any{[]model.TransactionEvent{{ID: 1}, {ID: 2}}, nil},
+			expectedStatus:  http.StatusCreated,
+			expectedCreated: 2,
+			expectedResults: []string{"created", "created"},
 		},
-		"error internal server error": {
-			testURL:        "/api/transactions/wrong-format",
-			mockFirstErr:   []any{nil, errors.New("")},
-			expectedStatus: http.StatusInternalServerError,
+		"error atomic bulk with an invalid item creates nothing": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        `[{"userId": 1, "amount": "1500", "currency": "IDR", "status": "pending"}, {"userId": 1, "amount": "1500", "currency": "IDR", "status": "refunded"}]`,
+			expectedUserIDs: []uint{1},
+			mockFindErr:     []any{[]model.User{{ID: 1}}, nil},
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedFailed:  1,
+			expectedResults: []string{"skipped", "failed"},
+		},
+		"successfully created the valid items of a partial bulk": {
+			testURL:         "/api/transactions/bulk?mode=partial",
+			contentType:     "application/json",
+			mockBody:        `[{"userId": 3, "amount": "1", "currency": "IDR", "status": "pending"}, {"userId": 1, "amount": "1500", "currency": "IDR", "status": "pending"}, {"userId": 1, "amount": "1.5", "currency": "JPY", "status": "pending"}, {"userId": "one"}]`,
+			expectedUserIDs: []uint{3, 1},
+			mockFindErr:     []any{[]model.User{{ID: 1}}, nil},
+			mockCreateErr:   []any{created[:1], nil},
+			mockEventErr:    []any{[]model.TransactionEvent{{ID: 1}}, nil},
+			expectedStatus:  http.StatusMultiStatus,
+			expectedCreated: 1,
+			expectedFailed:  3,
+			expectedResults: []string{"failed", "created", "failed", "failed"},
+		},
+		"error every item of a partial bulk failed": {
+			testURL:         "/api/transactions/bulk?mode=partial",
+			contentType:     "application/json",
+			mockBody:        `[{"userId": 1, "amount": "0", "currency": "IDR", "status": "pending"}]`,
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedFailed:  1,
+			expectedResults: []string{"failed"},
+		},
+		"error invalid mode": {
+			testURL:        "/api/transactions/bulk?mode=some",
+			contentType:    "application/json",
+			mockBody:       twoItems,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error empty bulk": {
+			testURL:        "/api/transactions/bulk",
+			contentType:    "application/json",
+			mockBody:       `[]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error body is not an array": {
+			testURL:        "/api/transactions/bulk",
+			contentType:    "application/json",
+			mockBody:       `{"userId": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user internal server error": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        twoItems,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+		"error user deleted before the transactions were inserted": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        twoItems,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockLockErr:     []any{[]model.User{{ID: 1}}, nil},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"error cannot insert transactions into database": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        twoItems,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockCreateErr:   []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+		"error cannot record created events": {
+			testURL:         "/api/transactions/bulk",
+			contentType:     "application/json",
+			mockBody:        twoItems,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockCreateErr:   []any{created, nil},
+			mockEventErr:    []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
 		},
 	}
 
@@ -218,84 +524,217 @@ func TestGetTransactionById(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
 			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := transactioncontroller.NewTransactionController(
 				mockTransactionRepo,
 				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
 			)
 
-			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockUserRepo.On("Find", mock.Anything, repository.Filter{Where: repository.In("id", test.expectedUserIDs)}).Return(test.mockFindErr...).Once()
+			mockLockErr := test.mockLockErr
+			if mockLockErr == nil {
+				mockLockErr = test.mockFindErr
+			}
+			mockUserRepo.On("Find", mock.Anything, mock.Anything).Return(mockLockErr...).Maybe()
+			mockTransactionRepo.On("CreateInBatches", mock.Anything, mock.Anything, mock.Anything).Return(test.mockCreateErr...).Once()
+			mockEventRepo.On("CreateInBatches", mock.Anything, mock.MatchedBy(func(events []model.TransactionEvent) bool {
+				for _, event := range events {
+					if event.Type != model.EventCreated || event.Actor != "anonymous" {
+						return false
+					}
+				}
+				return len(events) > 0
+			}), mock.Anything).Return(test.mockEventErr...).Once()
 
 			router := setUpRouter()
-			router.GET("/api/transactions/:id", controller.GetTransactionById)
+			router.POST("/api/transactions/bulk", controller.CreateTransactions)
 
 			w := httptest.NewRecorder()
 
-			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
-			req.Header.Set("Content-Type", "application/json")
+			req, _ := http.NewRequest(http.MethodPost, test.testURL, strings.NewReader(test.mockBody))
+			req.Header.Set("Content-Type", test.contentType)
 
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedResults != nil {
+				var res struct {
+					Data dto.TransactionBulkResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+
+				results := make([]string, 0, len(res.Data.Results))
+				for _, result := range res.Data.Results {
+					results = append(results, result.Result)
+				}
+				assert.Equal(t, test.expectedResults, results)
+				assert.Equal(t, test.expectedCreated, res.Data.Created)
+				assert.Equal(t, test.expectedFailed, res.Data.Failed)
+			}
 		})
 	}
 }
 
-func TestUpdateTransaction(t *testing.T) {
+func TestImportTransactions(t *testing.T) {
+	twoRows := "userId,amount,currency,status\n1,1500,IDR,pending\n2,12.5,USD,success\n"
+	created := []model.Transaction{
+		{ID: 1, UserID: 1, Amount: 150000, Currency: "IDR", Status: "pending"},
+		{ID: 2, UserID: 2, Amount: 1250, Currency: "USD", Status: "success"},
+	}
+
 	testCases := map[string]struct {
-		testURL        string
-		mockBody       any
-		mockFirstErr   []any
-		mockSaveErr    []any
-		expectedStatus int
+		testURL          string
+		contentType      string
+		mockBody         string
+		multipart        bool
+		expectedUserIDs  []uint
+		mockFindErr      []any
+		mockLockErr      []any
+		mockCreateErr    []any
+		mockEventErr     []any
+		expectedStatus   int
+		expectedImported int
+		expectedLines    []int
+		expectedReport   string
 	}{
-		"successfully updated transaction status": {
-			testURL: "/api/transactions/1",
-			mockBody: &dto.TransactionUpdate{
-				Status: "success",
-			},
-			mockFirstErr: []any{&model.Transaction{ID: 1}, nil},
-			mockSaveErr: []any{&model.Transaction{
-				Status: "success",
-			}, nil},
-			expectedStatus: http.StatusOK,
+		"successfully imported transactions": {
+			testURL:          "/api/transactions/import",
+			contentType:      "text/csv",
+			mockBody:         twoRows,
+			expectedUserIDs:  []uint{1, 2},
+			mockFindErr:      []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockCreateErr:    []any{created, nil},
+			mockEventErr:     []any{[]model.TransactionEvent{{ID: 1}, {ID: 2}}, nil},
+			expectedStatus:   http.StatusCreated,
+			expectedImported: 2,
+			expectedLines:    []int{},
 		},
-		"error cannot bind payload into json": {
-			testURL:        "/api/transactions/1",
-			mockBody:       "wrong-format",
-			expectedStatus: http.StatusInternalServerError,
+		"successfully imported a form file with mapped columns": {
+			testURL:          "/api/transactions/import?column[userId]=user_id&column[amount]=Total",
+			multipart:        true,
+			mockBody:         "\ufeffUSER_ID,total,currency,status,note\n1,1500,IDR,pending,first\n",
+			expectedUserIDs:  []uint{1},
+			mockFindErr:      []any{[]model.User{{ID: 1}}, nil},
+			mockCreateErr:    []any{created[:1], nil},
+			mockEventErr:     []any{[]model.TransactionEvent{{ID: 1}}, nil},
+			expectedStatus:   http.StatusCreated,
+			expectedImported: 1,
+			expectedLines:    []int{},
 		},
-		"error status must be success, pending, or failed": {
-			testURL: "/api/transactions/1",
-			mockBody: &dto.TransactionUpdate{
-				Status: "qwer",
-			},
-			expectedStatus: http.StatusInternalServerError,
+		"successfully imported the valid rows": {
+			testURL:          "/api/transactions/import",
+			contentType:      "text/csv",
+			mockBody:         "userId,amount,currency,status\n3,1,IDR,pending\n1,1500,IDR,pending\n1,1.5,JPY,pending\none,1,IDR,pending\n1,1\n",
+			expectedUserIDs:  []uint{3, 1},
+			mockFindErr:      []any{[]model.User{{ID: 1}}, nil},
+			mockCreateErr:    []any{created[:1], nil},
+			mockEventErr:     []any{[]model.TransactionEvent{{ID: 1}}, nil},
+			expectedStatus:   http.StatusMultiStatus,
+			expectedImported: 1,
+			expectedLines:    []int{2, 4, 5, 6},
 		},
-		"error transaction not found": {
-			testURL: "/api/transactions/1",
-			mockBody: &dto.TransactionUpdate{
-				Status: "success",
-			},
-			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
-			expectedStatus: http.StatusNotFound,
+		"successfully checked a dry run": {
+			testURL:         "/api/transactions/import?dryRun=true",
+			contentType:     "text/csv",
+			mockBody:        "userId,amount,currency,status\n1,1500,IDR,pending\n2,0,IDR,pending\n",
+			expectedUserIDs: []uint{1},
+			mockFindErr:     []any{[]model.User{{ID: 1}}, nil},
+			expectedStatus:  http.StatusOK,
+			expectedLines:   []int{3},
 		},
-		"error transaction internal server error": {
-			testURL: "/api/transactions/1",
-			mockBody: &dto.TransactionUpdate{
-				Status: "success",
-			},
-			mockFirstErr:   []any{(*model.Transaction)(nil), errors.New("")},
-			expectedStatus: http.StatusInternalServerError,
+		"successfully downloaded the error report": {
+			testURL:         "/api/transactions/import?dryRun=true&report=csv",
+			contentType:     "text/csv",
+			mockBody:        "userId,amount,currency,status\n1,1500,IDR,pending\n2,0,XYZ,pending\n",
+			expectedUserIDs: []uint{1},
+			mockFindErr:     []any{[]model.User{{ID: 1}}, nil},
+			expectedStatus:  http.StatusOK,
+			expectedReport:  "line,userId,amount,currency,status,error\n3,2,0,XYZ,pending,currency: currency must be an ISO 4217 code such as IDR or JPY\n",
 		},
-		"error cannot updated transaction status into database": {
-			testURL: "/api/transactions/1",
-			mockBody: &dto.TransactionUpdate{
-				Status: "success",
-			},
-			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
-			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("")},
-			expectedStatus: http.StatusInternalServerError,
+		"error every row failed": {
+			testURL:         "/api/transactions/import",
+			contentType:     "text/csv",
+			mockBody:        "userId,amount,currency,status\n3,1500,IDR,pending\n",
+			expectedUserIDs: []uint{3},
+			mockFindErr:     []any{[]model.User{}, nil},
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedLines:   []int{2},
+		},
+		"error missing column": {
+			testURL:        "/api/transactions/import",
+			contentType:    "text/csv",
+			mockBody:       "userId,amount,status\n1,1500,pending\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error mapped column is not an import column": {
+			testURL:        "/api/transactions/import?column[note]=memo",
+			contentType:    "text/csv",
+			mockBody:       twoRows,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error empty csv": {
+			testURL:        "/api/transactions/import",
+			contentType:    "text/csv",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error csv without rows": {
+			testURL:        "/api/transactions/import",
+			contentType:    "text/csv",
+			mockBody:       "userId,amount,currency,status\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid dry run": {
+			testURL:        "/api/transactions/import?dryRun=maybe",
+			contentType:    "text/csv",
+			mockBody:       twoRows,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid report": {
+			testURL:        "/api/transactions/import?report=xlsx",
+			contentType:    "text/csv",
+			mockBody:       twoRows,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error unsupported content type": {
+			testURL:        "/api/transactions/import",
+			contentType:    "application/json",
+			mockBody:       `[]`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		"error user internal server error": {
+			testURL:         "/api/transactions/import",
+			contentType:     "text/csv",
+			mockBody:        twoRows,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+		"error user deleted before the rows were inserted": {
+			testURL:         "/api/transactions/import",
+			contentType:     "text/csv",
+			mockBody:        twoRows,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockLockErr:     []any{[]model.User{{ID: 2}}, nil},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"error cannot insert transactions into database": {
+			testURL:         "/api/transactions/import",
+			contentType:     "text/csv",
+			mockBody:        twoRows,
+			expectedUserIDs: []uint{1, 2},
+			mockFindErr:     []any{[]model.User{{ID: 1}, {ID: 2}}, nil},
+			mockCreateErr:   []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
 		},
 	}
 
@@ -303,60 +742,188 @@ func TestUpdateTransaction(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
 			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := transactioncontroller.NewTransactionController(
 				mockTransactionRepo,
 				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
 			)
 
-			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
-			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+			mockUserRepo.On("Find", mock.Anything, repository.Filter{Where: repository.In("id", test.expectedUserIDs)}).Return(test.mockFindErr...).Once()
+			mockLockErr := test.mockLockErr
+			if mockLockErr == nil {
+				mockLockErr = test.mockFindErr
+			}
+			mockUserRepo.On("Find", mock.Anything, mock.Anything).Return(mockLockErr...).Maybe()
+			mockTransactionRepo.On("CreateInBatches", mock.Anything, mock.Anything, mock.Anything).Return(test.mockCreateErr...).Once()
+			mockEventRepo.On("CreateInBatches", mock.Anything, mock.MatchedBy(func(events []model.TransactionEvent) bool {
+				for _, event := range events {
+					if event.Type != model.EventCreated || event.Actor != "anonymous" {
+						return false
+					}
+				}
+				return len(events) > 0
+			}), mock.Anything).Return(test.mockEventErr...).Once()
 
 			router := setUpRouter()
-			router.PUT("/api/transactions/:id", controller.UpdateTransaction)
+			router.POST("/api/transactions/import", controller.ImportTransactions)
 
 			w := httptest.NewRecorder()
 
-			body, _ := json.Marshal(test.mockBody)
-			req, _ := http.NewRequest(http.MethodPut, test.testURL, bytes.NewBuffer(body))
-			req.Header.Set("Content-Type", "application/json")
+			body, contentType := strings.NewReader(test.mockBody), test.contentType
+			if test.multipart {
+				var form bytes.Buffer
+				writer := multipart.NewWriter(&form)
+				file, _ := writer.CreateFormFile("file", "transactions.csv")
+				_, _ = file.Write([]byte(test.mockBody))
+				_ = writer.Close()
+				body, contentType = strings.NewReader(form.String()), writer.FormDataContentType()
+			}
+
+			req, _ := http.NewRequest(http.MethodPost, test.testURL, body)
+			req.Header.Set("Content-Type", contentType)
 
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedLines != nil {
+				var res struct {
+					Data dto.TransactionImportResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+
+				lines := make([]int, 0, len(res.Data.Errors))
+				for _, rowErr := range res.Data.Errors {
+					lines = append(lines, rowErr.Line)
+				}
+				assert.Equal(t, test.expectedLines, lines)
+				assert.Equal(t, test.expectedImported, res.Data.Imported)
+			}
+			if test.expectedReport != "" {
+				assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Equal(t, test.expectedReport, w.Body.String())
+			}
+			if strings.Contains(test.testURL, "dryRun=true") {
+				mockTransactionRepo.AssertNotCalled(t, "CreateInBatches", mock.Anything, mock.Anything, mock.Anything)
+			}
 		})
 	}
 }
 
-func TestDeleteTransaction(t *testing.T) {
+func TestUpdateTransactionStatuses(t *testing.T) {
+	selected := []model.Transaction{
+		{ID: 1, Status: "pending"},
+		{ID: 2, Status: "success"},
+		{ID: 3, Status: "failed"},
+	}
+
 	testCases := map[string]struct {
-		testURL        string
-		mockFirstErr   []any
-		mockSaveErr    []any
-		expectedStatus int
+		mockBody         string
+		mockCountErr     []any
+		mockFindErr      []any
+		expectedUpdate   repository.Expression
+		mockUpdateErr    []any
+		mockEventErr     []any
+		expectedStatus   int
+		expectedMessage  string
+		expectedResponse *dto.TransactionBulkChangeResponse
 	}{
-		"successfully deleted transaction": {
-			testURL:      "/api/transactions/1",
-			mockFirstErr: []any{&model.Transaction{ID: 1}, nil},
-			mockSaveErr: []any{&model.Transaction{
-				IsDeleted: true,
-			}, nil},
+		"successfully failed the pending transactions of an id list": {
+			mockBody:       `{"ids": [1, 2, 3, 4, 4], "status": "failed"}`,
+			mockFindErr:    []any{selected, nil},
+			expectedUpdate: repository.All(repository.In("id", []uint{1}), repository.In("status", []string{"pending"})),
+			mockUpdateErr:  []any{[]model.Transaction{{ID: 1, Status: "failed"}}, nil},
+			mockEventErr:   []any{[]model.TransactionEvent{{ID: 1}}, nil},
 			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Affected:    1,
+				Skipped:     2,
+				NotFound:    1,
+				SkippedIDs:  []uint{2, 3},
+				NotFoundIDs: []uint{4},
+			},
 		},
-		"error transaction not found": {
-			testURL:        "/api/transactions/1",
-			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
-			expectedStatus: http.StatusNotFound,
+		"successfully failed the transactions of a filter": {
+			mockBody:       `{"filter": {"status": ["pending"]}, "status": "failed"}`,
+			mockCountErr:   []any{int64(2), nil},
+			mockFindErr:    []any{[]model.Transaction{{ID: 1, Status: "pending"}, {ID: 5, Status: "pending"}}, nil},
+			expectedUpdate: repository.All(repository.In("id", []uint{1, 5}), repository.In("status", []string{"pending"})),
+			mockUpdateErr:  []any{[]model.Transaction{{ID: 5, Status: "failed"}}, nil},
+			mockEventErr:   []any{[]model.TransactionEvent{{ID: 1}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Affected:    1,
+				Skipped:     1,
+				SkippedIDs:  []uint{1},
+				NotFoundIDs: []uint{},
+			},
+		},
+		"successfully skipped transactions that cannot change": {
+			mockBody:       `{"ids": [2, 3], "status": "failed"}`,
+			mockFindErr:    []any{selected[1:], nil},
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Skipped:     2,
+				SkippedIDs:  []uint{2, 3},
+				NotFoundIDs: []uint{},
+			},
+		},
+		"error cannot bind payload into json": {
+			mockBody:       `wrong-format`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid status": {
+			mockBody:        `{"ids": [1], "status": "qwer"}`,
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedMessage: "status must be pending, success, failed, cancelled, expired, partially_refunded or refunded",
+		},
+		"error neither ids nor filter": {
+			mockBody:       `{"status": "failed"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error both ids and filter": {
+			mockBody:       `{"ids": [1], "filter": {"status": ["pending"]}, "status": "failed"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error empty filter": {
+			mockBody:       `{"filter": {}, "status": "failed"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error invalid filter": {
+			mockBody:       `{"filter": {"status": ["qwer"]}, "status": "failed"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error filter matches too many transactions": {
+			mockBody:       `{"filter": {"status": ["pending"]}, "status": "failed"}`,
+			mockCountErr:   []any{int64(10001), nil},
+			expectedStatus: http.StatusRequestEntityTooLarge,
 		},
 		"error transaction internal server error": {
-			testURL:        "/api/transactions/1",
-			mockFirstErr:   []any{(*model.Transaction)(nil), errors.New("")},
+			mockBody:       `{"ids": [1], "status": "failed"}`,
+			mockFindErr:    []any{nil, errors.New("")},
 			expectedStatus: http.StatusInternalServerError,
 		},
-		"error cannot updated transaction status into database": {
-			testURL:        "/api/transactions/1",
-			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
-			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("")},
+		"error cannot update transactions into database": {
+			mockBody:       `{"ids": [1], "status": "failed"}`,
+			mockFindErr:    []any{selected[:1], nil},
+			expectedUpdate: repository.All(repository.In("id", []uint{1}), repository.In("status", []string{"pending"})),
+			mockUpdateErr:  []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot record status changed events": {
+			mockBody:       `{"ids": [1], "status": "failed"}`,
+			mockFindErr:    []any{selected[:1], nil},
+			expectedUpdate: repository.All(repository.In("id", []uint{1}), repository.In("status", []string{"pending"})),
+			mockUpdateErr:  []any{[]model.Transaction{{ID: 1, Status: "failed"}}, nil},
+			mockEventErr:   []any{nil, errors.New("")},
 			expectedStatus: http.StatusInternalServerError,
 		},
 	}
@@ -365,26 +932,2294 @@ func TestDeleteTransaction(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
 			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := transactioncontroller.NewTransactionController(
 				mockTransactionRepo,
 				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
 			)
 
-			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
-			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+			mockTransactionRepo.On("Count", mock.Anything, mock.Anything).Return(test.mockCountErr...).Once()
+			mockTransactionRepo.On("Find", mock.Anything, mock.Anything).Return(test.mockFindErr...).Once()
+			mockTransactionRepo.On("UpdateAll", mock.Anything, repository.Filter{Where: test.expectedUpdate}, mock.Anything).Return(test.mockUpdateErr...).Once()
+
+			mockEventRepo.On("CreateInBatches", mock.Anything, mock.MatchedBy(func(events []model.TransactionEvent) bool {
+				for _, event := range events {
+					if event.Type != model.EventStatusChanged || event.OldValues == nil {
+						return false
+					}
+				}
+				return true
+			}), mock.Anything).Return(test.mockEventErr...).Once()
 
 			router := setUpRouter()
-			router.DELETE("/api/transactions/:id", controller.DeleteTransaction)
+			router.POST("/api/transactions/bulk-status", controller.UpdateTransactionStatuses)
 
 			w := httptest.NewRecorder()
 
-			req, _ := http.NewRequest(http.MethodDelete, test.testURL, nil)
+			req, _ := http.NewRequest(http.MethodPost, "/api/transactions/bulk-status", strings.NewReader(test.mockBody))
 			req.Header.Set("Content-Type", "application/json")
 
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedResponse != nil {
+				var res struct {
+					Data dto.TransactionBulkChangeResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, *test.expectedResponse, res.Data)
+			}
+			if test.expectedMessage != "" {
+				var res struct {
+					Errors []apperror.FieldError `json:"errors"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, test.expectedMessage, res.Errors[0].Message)
+			}
+		})
+	}
+}
+
+func TestDeleteTransactions(t *testing.T) {
+	testCases := map[string]struct {
+		mockBody         string
+		mockFindErr      []any
+		mockUpdateErr    []any
+		mockEventErr     []any
+		expectedWhere    repository.Expression
+		expectedStatus   int
+		expectedResponse *dto.TransactionBulkChangeResponse
+	}{
+		"successfully deleted transactions of an id list": {
+			mockBody:       `{"ids": [1, 2, 3]}`,
+			mockFindErr:    []any{[]model.Transaction{{ID: 1}, {ID: 2}}, nil},
+			mockUpdateErr:  []any{[]model.Transaction{{ID: 1, SoftDelete: model.Deleted(time.Now())}, {ID: 2, SoftDelete: model.Deleted(time.Now())}}, nil},
+			mockEventErr:   []any{[]model.TransactionEvent{{ID: 1}, {ID: 2}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Affected:    2,
+				NotFound:    1,
+				SkippedIDs:  []uint{},
+				NotFoundIDs: []uint{3},
+			},
+		},
+		"successfully deleted paid transactions": {
+			mockBody: `{"ids": [1, 2, 3]}`,
+			mockFindErr: []any{[]model.Transaction{
+				{ID: 1, Status: model.StatusSuccess},
+				{ID: 2, Status: model.StatusPartiallyRefunded},
+				{ID: 3, Status: model.StatusRefunded},
+			}, nil},
+			mockUpdateErr: []any{[]model.Transaction{
+				{ID: 1, Status: model.StatusSuccess, SoftDelete: model.Deleted(time.Now())},
+				{ID: 2, Status: model.StatusPartiallyRefunded, SoftDelete: model.Deleted(time.Now())},
+				{ID: 3, Status: model.StatusRefunded, SoftDelete: model.Deleted(time.Now())},
+			}, nil},
+			mockEventErr:   []any{[]model.TransactionEvent{{ID: 1}, {ID: 2}, {ID: 3}}, nil},
+			expectedWhere:  repository.In("id", []uint{1, 2, 3}),
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Affected:    3,
+				SkippedIDs:  []uint{},
+				NotFoundIDs: []uint{},
+			},
+		},
+		"successfully skipped a transaction deleted in between": {
+			mockBody:       `{"ids": [1, 2]}`,
+			mockFindErr:    []any{[]model.Transaction{{ID: 1}, {ID: 2}}, nil},
+			mockUpdateErr:  []any{[]model.Transaction{{ID: 1, SoftDelete: model.Deleted(time.Now())}}, nil},
+			mockEventErr:   []any{[]model.TransactionEvent{{ID: 1}}, nil},
+			expectedWhere:  repository.In("id", []uint{1, 2}),
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				Affected:    1,
+				Skipped:     1,
+				SkippedIDs:  []uint{2},
+				NotFoundIDs: []uint{},
+			},
+		},
+		"successfully deleted nothing when no transaction is found": {
+			mockBody:       `{"ids": [3]}`,
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+			expectedResponse: &dto.TransactionBulkChangeResponse{
+				NotFound:    1,
+				SkippedIDs:  []uint{},
+				NotFoundIDs: []uint{3},
+			},
+		},
+		"error neither ids nor filter": {
+			mockBody:       `{}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error cannot delete transactions into database": {
+			mockBody:       `{"ids": [1]}`,
+			mockFindErr:    []any{[]model.Transaction{{ID: 1}}, nil},
+			mockUpdateErr:  []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("Find", mock.Anything, mock.Anything).Return(test.mockFindErr...).Once()
+			mockTransactionRepo.On("UpdateAll", mock.Anything, mock.Anything, mock.MatchedBy(func(values map[string]interface{}) bool {
+				return values["is_deleted"] == true && values["deleted_at"] != nil
+			})).Return(test.mockUpdateErr...).Once()
+
+			mockEventRepo.On("CreateInBatches", mock.Anything, mock.MatchedBy(func(events []model.TransactionEvent) bool {
+				for _, event := range events {
+					if event.Type != model.EventDeleted {
+						return false
+					}
+				}
+				return true
+			}), mock.Anything).Return(test.mockEventErr...).Once()
+
+			router := setUpRouter()
+			router.POST("/api/transactions/bulk-delete", controller.DeleteTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/transactions/bulk-delete", strings.NewReader(test.mockBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedResponse != nil {
+				var res struct {
+					Data dto.TransactionBulkChangeResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, *test.expectedResponse, res.Data)
+			}
+			if test.expectedWhere != nil {
+				mockTransactionRepo.AssertCalled(t, "UpdateAll", mock.Anything, repository.Filter{Where: test.expectedWhere}, mock.Anything)
+			}
+			if test.mockUpdateErr == nil {
+				mockTransactionRepo.AssertNotCalled(t, "UpdateAll", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetTransactions(t *testing.T) {
+	cursor := pagination.Cursor{CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: 5}
+	userAndStatus := repository.All(
+		repository.In("user_id", []uint{1}),
+		repository.In("status", []string{"pending"}),
+	)
+	userOrStatuses := repository.Any(
+		repository.In("user_id", []uint{1, 2}),
+		repository.In("status", []string{"pending", "failed"}),
+	)
+	ranges := repository.All(
+		repository.In("currency", []string{"IDR"}),
+		repository.All(repository.Gte("amount", int64(1000)), repository.Lte("amount", int64(2000))),
+		repository.All(
+			repository.Gte("created_at", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+			repository.Lt("created_at", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)),
+		),
+		repository.All(repository.Lte("updated_at", time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC))),
+	)
+	annotations := repository.All(
+		repository.Any(repository.Has("tags", "promo"), repository.Has("tags", "vip")),
+		repository.KeyEq("metadata", "channel", "web"),
+		repository.KeyEq("metadata", "orderId", "123"),
+	)
+
+	testCases := map[string]struct {
+		testURL        string
+		expectedWhere  repository.Expression
+		expectedFilter repository.Filter
+		mockCountErr   []any
+		mockFindErr    []any
+		expectedStatus int
+	}{
+		"successfully get transaction with query": {
+			testURL:       "/api/transactions?userId=1&status=pending",
+			expectedWhere: userAndStatus,
+			expectedFilter: repository.Filter{
+				Where: userAndStatus,
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr: []any{int64(1), nil},
+			mockFindErr: []any{[]model.Transaction{
+				{ID: 1,
+					UserID:    1,
+					Amount:    1,
+					Status:    "pending",
+					CreatedAt: time.Now(),
+				},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction with any match": {
+			testURL:       "/api/transactions?userId=1,2&status=pending&status=failed&match=any",
+			expectedWhere: userOrStatuses,
+			expectedFilter: repository.Filter{
+				Where: userOrStatuses,
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction with tags and metadata": {
+			testURL:       "/api/transactions?tag=Promo,vip&metadata.orderId=123&metadata.channel=web",
+			expectedWhere: annotations,
+			expectedFilter: repository.Filter{
+				Where: annotations,
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid metadata key": {
+			testURL:        "/api/transactions?metadata.order%20id=123",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"successfully get transaction with ranges": {
+			testURL:       "/api/transactions?currency=idr&minAmount=10&maxAmount=20&createdFrom=2025-01-01&createdTo=2025-01-31&updatedTo=2025-01-31T12:00:00Z",
+			expectedWhere: ranges,
+			expectedFilter: repository.Filter{
+				Where: ranges,
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction with sort": {
+			testURL:       "/api/transactions?sort=-amount,createdAt",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(),
+				Sort: []repository.Sort{
+					{Field: "amount", Descending: true},
+					{Field: "created_at"},
+					{Field: "id"},
+				},
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error injected status": {
+			testURL:        "/api/transactions?status=x'%20OR%20'1'='1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid user id": {
+			testURL:        "/api/transactions?userId=1,abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid match": {
+			testURL:        "/api/transactions?match=some",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid sort": {
+			testURL:        "/api/transactions?sort=status",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error amount range without a single currency": {
+			testURL:        "/api/transactions?currency=IDR,JPY&minAmount=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error unknown currency": {
+			testURL:        "/api/transactions?currency=XYZ",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error min amount greater than max amount": {
+			testURL:        "/api/transactions?currency=IDR&minAmount=20&maxAmount=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error created from after created to": {
+			testURL:        "/api/transactions?createdFrom=2025-02-01&createdTo=2025-01-01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid updated from": {
+			testURL:        "/api/transactions?updatedFrom=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error sort with cursor": {
+			testURL:        "/api/transactions?sort=amount&after=" + cursor.Encode(),
+			expectedStatus: http.StatusBadRequest,
+		},
+		"successfully get transaction without query": {
+			testURL:       "/api/transactions",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(),
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr: []any{int64(2), nil},
+			mockFindErr: []any{[]model.Transaction{
+				{ID: 1,
+					UserID:    1,
+					Amount:    1,
+					Status:    "pending",
+					CreatedAt: time.Now(),
+				},
+				{ID: 2,
+					UserID:    2,
+					Amount:    2,
+					Status:    "success",
+					CreatedAt: time.Now(),
+				},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction by page with capped page size": {
+			testURL:       "/api/transactions?page=3&pageSize=1000",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where:  repository.All(),
+				Sort:   pagination.Newest(),
+				Limit:  pagination.MaxPageSize + 1,
+				Offset: 2 * pagination.MaxPageSize,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction after cursor": {
+			testURL:       "/api/transactions?pageSize=10&after=" + cursor.Encode(),
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(repository.All(), pagination.After(cursor)),
+				Sort:  pagination.Newest(),
+				Limit: 11,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get transaction before cursor": {
+			testURL:       "/api/transactions?pageSize=10&before=" + cursor.Encode(),
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(repository.All(), pagination.Before(cursor)),
+				Sort:  pagination.Oldest(),
+				Limit: 11,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.Transaction{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid cursor": {
+			testURL:        "/api/transactions?after=wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error after and before cursor together": {
+			testURL:        "/api/transactions?after=" + cursor.Encode() + "&before=" + cursor.Encode(),
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error request canceled": {
+			testURL:        "/api/transactions",
+			expectedWhere:  repository.All(),
+			mockCountErr:   []any{int64(0), context.Canceled},
+			expectedStatus: apperror.StatusClientClosedRequest,
+		},
+		"error count transactions": {
+			testURL:        "/api/transactions",
+			expectedWhere:  repository.All(),
+			mockCountErr:   []any{int64(0), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error get transactions": {
+			testURL:       "/api/transactions",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(),
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot bind payload into json": {
+			testURL:        "/api/transactions?minAmount=wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("Count", mock.Anything, repository.Filter{Where: test.expectedWhere}).Return(test.mockCountErr...).Once()
+			mockTransactionRepo.On("Find", mock.Anything, test.expectedFilter).Return(test.mockFindErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions", controller.GetTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetTransactionsPageCursors(t *testing.T) {
+	newest := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	rows := []model.Transaction{
+		{ID: 3, CreatedAt: newest},
+		{ID: 2, CreatedAt: newest.Add(-time.Hour)},
+		{ID: 1, CreatedAt: newest.Add(-2 * time.Hour)},
+	}
+
+	mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+	mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+	mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+	mockLedger := new(mocks.MockLedger)
+	mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	controller := transactioncontroller.NewTransactionController(
+		mockTransactionRepo,
+		mockUserRepo,
+		mockEventRepo,
+		new(mocks.MockDatabaseRepository[model.Refund]),
+		new(mocks.MockTransactor),
+		mockLedger,
+		720*time.Hour,
+	)
+
+	mockTransactionRepo.On("Count", mock.Anything, mock.Anything).Return(int64(3), nil).Once()
+	mockTransactionRepo.On("Find", mock.Anything, mock.Anything).Return(rows, nil).Once()
+
+	router := setUpRouter()
+	router.GET("/api/transactions", controller.GetTransactions)
+
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/transactions?page=2&pageSize=2", nil)
+	router.ServeHTTP(w, req)
+
+	var res struct {
+		Data dto.Pagination[dto.TransactionResponse] `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, res.Data.TotalRecords)
+	assert.Equal(t, 2, len(res.Data.Data))
+	assert.Equal(t, pagination.Cursor{CreatedAt: rows[1].CreatedAt, ID: 2}.Encode(), res.Data.NextCursor)
+	assert.Equal(t, pagination.Cursor{CreatedAt: rows[0].CreatedAt, ID: 3}.Encode(), res.Data.PrevCursor)
+}
+
+func TestGetUserTransactions(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		mockFirstErr   []any
+		expectedWhere  repository.Expression
+		mockCountErr   []any
+		mockFindErr    []any
+		expectedStatus int
+	}{
+		"successfully get transactions of user": {
+			testURL:       "/api/users/1/transactions?status=success",
+			mockFirstErr:  []any{&model.User{ID: 1}, nil},
+			expectedWhere: repository.All(repository.Eq("user_id", uint(1)), repository.All(repository.In("status", []string{"success"}))),
+			mockCountErr:  []any{int64(1), nil},
+			mockFindErr: []any{[]model.Transaction{
+				{ID: 1, UserID: 1, Amount: 1, Status: "success", CreatedAt: time.Now()},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/users/wrong-format/transactions",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user not found": {
+			testURL:        "/api/users/1/transactions",
+			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error user internal server error": {
+			testURL:        "/api/users/1/transactions",
+			mockFirstErr:   []any{(*model.User)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error invalid query": {
+			testURL:        "/api/users/1/transactions?status=qwer",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Count", mock.Anything, repository.Filter{Where: test.expectedWhere}).Return(test.mockCountErr...).Once()
+			mockTransactionRepo.On("Find", mock.Anything, mock.Anything).Return(test.mockFindErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/users/:id/transactions", controller.GetUserTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetTransactionById(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		ifNoneMatch    string
+		mockFirstErr   []any
+		expectedStatus int
+		expectedETag   string
+	}{
+		"successfully get transaction by id": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 2}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+		},
+		"successfully not modified since the client read it": {
+			testURL:        "/api/transactions/1",
+			ifNoneMatch:    `W/"2"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 2}}, nil},
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"2"`,
+		},
+		"successfully get transaction changed since the client read it": {
+			testURL:        "/api/transactions/1",
+			ifNoneMatch:    `"1"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 2}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/10",
+			mockFirstErr:   []any{nil, gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error internal server error": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error request timed out": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{nil, context.DeadlineExceeded},
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions/:id", controller.GetTransactionById)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+			if test.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestGetTransactionStatuses(t *testing.T) {
+	mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+	mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+	mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+	mockLedger := new(mocks.MockLedger)
+	mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	controller := transactioncontroller.NewTransactionController(
+		mockTransactionRepo,
+		mockUserRepo,
+		mockEventRepo,
+		new(mocks.MockDatabaseRepository[model.Refund]),
+		new(mocks.MockTransactor),
+		mockLedger,
+		720*time.Hour,
+	)
+
+	router := setUpRouter()
+	router.GET("/api/transactions/statuses", controller.GetTransactionStatuses)
+
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/transactions/statuses", nil)
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	var body struct {
+		Data []dto.TransactionStatusResponse `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, dto.TransactionStatusResponse{
+		Status:      "pending",
+		Initial:     true,
+		Transitions: []string{"success", "failed", "cancelled", "expired"},
+	}, body.Data[0])
+	assert.Equal(t, dto.TransactionStatusResponse{
+		Status:      "success",
+		Initial:     true,
+		Transitions: []string{},
+		Refundable:  true,
+	}, body.Data[1])
+}
+
+func TestGetTransactionTransitions(t *testing.T) {
+	testCases := map[string]struct {
+		testURL             string
+		mockFirstErr        []any
+		expectedStatus      int
+		expectedTransitions []string
+	}{
+		"successfully get transitions of pending transaction": {
+			testURL:             "/api/transactions/1/transitions",
+			mockFirstErr:        []any{&model.Transaction{ID: 1, Status: "pending"}, nil},
+			expectedStatus:      http.StatusOK,
+			expectedTransitions: []string{"success", "failed", "cancelled", "expired"},
+		},
+		"successfully get transitions of final transaction": {
+			testURL:             "/api/transactions/1/transitions",
+			mockFirstErr:        []any{&model.Transaction{ID: 1, Status: "refunded"}, nil},
+			expectedStatus:      http.StatusOK,
+			expectedTransitions: []string{},
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format/transitions",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/1/transitions",
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions/:id/transitions", controller.GetTransactionTransitions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedTransitions != nil {
+				var body struct {
+					Data dto.TransactionTransitionsResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &body)
+				assert.Equal(t, test.expectedTransitions, body.Data.Transitions)
+			}
+		})
+	}
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	testCases := map[string]struct {
+		testURL          string
+		mockBody         any
+		ifMatch          string
+		mockFirstErr     []any
+		mockSaveErr      []any
+		mockEventErr     []any
+		mockPostErr      error
+		expectedJournals []string
+		expectedStatus   int
+	}{
+		"successfully updated transaction status": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr: []any{&model.Transaction{ID: 1, UserID: 2, Amount: 10000, Currency: "IDR", Status: "pending"}, nil},
+			mockSaveErr: []any{&model.Transaction{
+				ID:       1,
+				UserID:   2,
+				Amount:   10000,
+				Currency: "IDR",
+				Status:   "success",
+			}, nil},
+			mockEventErr:     []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedJournals: []string{"transaction:1"},
+			expectedStatus:   http.StatusOK,
+		},
+		"successfully cancelled pending transaction": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "cancelled",
+			},
+			mockFirstErr: []any{&model.Transaction{ID: 1, Status: "pending"}, nil},
+			mockSaveErr: []any{&model.Transaction{
+				Status: "cancelled",
+			}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully kept the current status": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "success"}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("must not be saved")},
+			expectedStatus: http.StatusOK,
+		},
+		"error success cannot go back to pending": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "pending",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "success"}, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error failed is final": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "failed"}, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error cannot bind payload into json": {
+			testURL:        "/api/transactions/1",
+			mockBody:       "wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error status must be success, pending, or failed": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "qwer",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error transaction not found": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error transaction internal server error": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{(*model.Transaction)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot updated transaction status into database": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "pending"}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"successfully updated the version the client read": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			ifMatch:          `"3"`,
+			mockFirstErr:     []any{&model.Transaction{ID: 1, Status: "pending", Version: model.Version{Version: 3}}, nil},
+			mockSaveErr:      []any{&model.Transaction{ID: 1, Status: "success", Version: model.Version{Version: 4}}, nil},
+			mockEventErr:     []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedJournals: []string{"transaction:1"},
+			expectedStatus:   http.StatusOK,
+		},
+		"error if-match does not match the current version": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			ifMatch:        `"2"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "pending", Version: model.Version{Version: 3}}, nil},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error transaction changed while saving": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "pending", Version: model.Version{Version: 3}}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), &repository.ConflictError{Version: 3}},
+			expectedStatus: http.StatusConflict,
+		},
+		"error transaction changed while saving with if-match": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			ifMatch:        `"3"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "pending", Version: model.Version{Version: 3}}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), &repository.ConflictError{Version: 3}},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error cannot record status changed event": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: "pending"}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Status: "success"}, nil},
+			mockEventErr:   []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot post payment journal": {
+			testURL: "/api/transactions/1",
+			mockBody: &dto.TransactionUpdate{
+				Status: "success",
+			},
+			mockFirstErr:     []any{&model.Transaction{ID: 1, Status: "pending"}, nil},
+			mockSaveErr:      []any{&model.Transaction{ID: 1, Status: "success"}, nil},
+			mockEventErr:     []any{&model.TransactionEvent{ID: 1}, nil},
+			mockPostErr:      errors.New(""),
+			expectedJournals: []string{"transaction:1"},
+			expectedStatus:   http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(test.mockPostErr)
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == model.EventStatusChanged && event.Actor == "anonymous"
+			})).Return(test.mockEventErr...)
+
+			router := setUpRouter()
+			router.PUT("/api/transactions/:id", controller.UpdateTransaction)
+
+			w := httptest.NewRecorder()
+
+			body, _ := json.Marshal(test.mockBody)
+			req, _ := http.NewRequest(http.MethodPut, test.testURL, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var journals []string
+			for _, call := range mockLedger.Calls {
+				for _, journal := range call.Arguments.Get(1).([]ledger.Journal) {
+					journals = append(journals, journal.Reference)
+				}
+			}
+			assert.Equal(t, test.expectedJournals, journals)
+		})
+	}
+}
+
+func TestPatchTransaction(t *testing.T) {
+	pending := &model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", Status: "pending", Version: model.Version{Version: 1}}
+	settled := &model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", Status: "success", Version: model.Version{Version: 1}}
+	annotated := &model.Transaction{
+		ID:          1,
+		Amount:      150000,
+		Currency:    "IDR",
+		Status:      "success",
+		Description: "Order #1",
+		Metadata:    model.Metadata{"orderId": "1", "channel": "web"},
+		Tags:        model.Tags{"promo"},
+		Version:     model.Version{Version: 1},
+	}
+
+	testCases := map[string]struct {
+		contentType         string
+		ifMatch             string
+		mockBody            string
+		mockFirstErr        []any
+		mockSaveErr         []any
+		mockEventErr        []any
+		expectedEventType   string
+		expectedSaved       *model.Transaction
+		expectedAnnotations *model.Transaction
+		expectedStatus      int
+	}{
+		"successfully merge patched amount": {
+			contentType:       "application/merge-patch+json",
+			mockBody:          `{"amount": "2000.50"}`,
+			mockFirstErr:      []any{pending, nil},
+			mockSaveErr:       []any{&model.Transaction{ID: 1, Amount: 200050, Currency: "IDR", Status: "pending"}, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedSaved:     &model.Transaction{Amount: 200050, Currency: "IDR", Status: "pending"},
+			expectedStatus:    http.StatusOK,
+		},
+		"successfully merge patched currency and status": {
+			contentType:       "application/merge-patch+json",
+			mockBody:          `{"currency": "usd", "amount": 12.5, "status": "success"}`,
+			mockFirstErr:      []any{pending, nil},
+			mockSaveErr:       []any{&model.Transaction{ID: 1, Amount: 1250, Currency: "USD", Status: "success"}, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedSaved:     &model.Transaction{Amount: 1250, Currency: "USD", Status: "success"},
+			expectedStatus:    http.StatusOK,
+		},
+		"successfully patched status as application/json": {
+			contentType:       "application/json",
+			mockBody:          `{"status": "failed"}`,
+			mockFirstErr:      []any{pending, nil},
+			mockSaveErr:       []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", Status: "failed"}, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventStatusChanged,
+			expectedSaved:     &model.Transaction{Amount: 150000, Currency: "IDR", Status: "failed"},
+			expectedStatus:    http.StatusOK,
+		},
+		"successfully json patched amount after a passing test": {
+			contentType:       "application/json-patch+json",
+			mockBody:          `[{"op": "test", "path": "/amount", "value": 1500}, {"op": "replace", "path": "/amount", "value": "1750"}]`,
+			mockFirstErr:      []any{pending, nil},
+			mockSaveErr:       []any{&model.Transaction{ID: 1, Amount: 175000, Currency: "IDR", Status: "pending"}, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedSaved:     &model.Transaction{Amount: 175000, Currency: "IDR", Status: "pending"},
+			expectedStatus:    http.StatusOK,
+		},
+		"successfully kept the current values": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"amount": "1500.00", "currency": "IDR"}`,
+			mockFirstErr:   []any{pending, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("must not be saved")},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully merge patched metadata and tags of a settled transaction": {
+			contentType:       "application/merge-patch+json",
+			mockBody:          `{"metadata": {"channel": null, "note": "gift"}, "tags": ["Promo", "vip"]}`,
+			mockFirstErr:      []any{annotated, nil},
+			mockSaveErr:       []any{annotated, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedAnnotations: &model.Transaction{
+				Description: "Order #1",
+				Metadata:    model.Metadata{"orderId": "1", "note": "gift"},
+				Tags:        model.Tags{"promo", "vip"},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully json patched a tag": {
+			contentType:       "application/json-patch+json",
+			mockBody:          `[{"op": "add", "path": "/tags/-", "value": "vip"}]`,
+			mockFirstErr:      []any{annotated, nil},
+			mockSaveErr:       []any{annotated, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedAnnotations: &model.Transaction{
+				Description: "Order #1",
+				Metadata:    model.Metadata{"orderId": "1", "channel": "web"},
+				Tags:        model.Tags{"promo", "vip"},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully cleared description and metadata": {
+			contentType:       "application/merge-patch+json",
+			mockBody:          `{"description": null, "metadata": null}`,
+			mockFirstErr:      []any{annotated, nil},
+			mockSaveErr:       []any{annotated, nil},
+			mockEventErr:      []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedEventType: model.EventUpdated,
+			expectedAnnotations: &model.Transaction{
+				Metadata: model.Metadata{},
+				Tags:     model.Tags{"promo"},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully kept the current tags": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"tags": ["PROMO"]}`,
+			mockFirstErr:   []any{annotated, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("must not be saved")},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid patched metadata": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"metadata": {"order": {"id": 1}}}`,
+			mockFirstErr:   []any{annotated, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error unsupported content type": {
+			contentType:    "text/plain",
+			mockBody:       `{"status": "success"}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		"error invalid merge patch": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"status":`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error json patch is not an array": {
+			contentType:    "application/json-patch+json",
+			mockBody:       `{"status": "success"}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error json patch test failed": {
+			contentType:    "application/json-patch+json",
+			mockBody:       `[{"op": "test", "path": "/status", "value": "success"}, {"op": "replace", "path": "/status", "value": "refunded"}]`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error field cannot be patched": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"userId": 2}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error field cannot be removed": {
+			contentType:    "application/json-patch+json",
+			mockBody:       `[{"op": "remove", "path": "/currency"}]`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount must be positive": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"amount": "-1"}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount too precise for the new currency": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"amount": "12.50", "currency": "JPY"}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error invalid status": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"status": "qwer"}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error amount of a settled transaction": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"amount": "10"}`,
+			mockFirstErr:   []any{settled, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error illegal transition": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"status": "pending"}`,
+			mockFirstErr:   []any{settled, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error if-match does not match the current version": {
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"2"`,
+			mockBody:       `{"status": "success"}`,
+			mockFirstErr:   []any{pending, nil},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error transaction not found": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"status": "success"}`,
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error transaction changed while saving": {
+			contentType:    "application/merge-patch+json",
+			mockBody:       `{"status": "success"}`,
+			mockFirstErr:   []any{pending, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), &repository.ConflictError{Version: 1}},
+			expectedStatus: http.StatusConflict,
+		},
+		"error cannot record updated event": {
+			contentType:       "application/merge-patch+json",
+			mockBody:          `{"amount": "10"}`,
+			mockFirstErr:      []any{pending, nil},
+			mockSaveErr:       []any{&model.Transaction{ID: 1, Amount: 1000, Currency: "IDR", Status: "pending"}, nil},
+			mockEventErr:      []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedEventType: model.EventUpdated,
+			expectedStatus:    http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == test.expectedEventType && event.Actor == "anonymous"
+			})).Return(test.mockEventErr...)
+
+			router := setUpRouter()
+			router.PATCH("/api/transactions/:id", controller.PatchTransaction)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPatch, "/api/transactions/1", strings.NewReader(test.mockBody))
+			req.Header.Set("Content-Type", test.contentType)
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedSaved != nil {
+				mockTransactionRepo.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					return transaction.Amount == test.expectedSaved.Amount &&
+						transaction.Currency == test.expectedSaved.Currency &&
+						transaction.Status == test.expectedSaved.Status
+				}))
+			}
+			if test.expectedAnnotations != nil {
+				mockTransactionRepo.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					return transaction.Description == test.expectedAnnotations.Description &&
+						reflect.DeepEqual(transaction.Metadata, test.expectedAnnotations.Metadata) &&
+						reflect.DeepEqual(transaction.Tags, test.expectedAnnotations.Tags)
+				}))
+			}
+		})
+	}
+}
+
+func TestCreateRefund(t *testing.T) {
+	settled := &model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", Status: "success", Version: model.Version{Version: 1}}
+	partial := &model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", RefundedAmount: 100000, Status: "partially_refunded", Version: model.Version{Version: 2}}
+
+	testCases := map[string]struct {
+		testURL          string
+		ifMatch          string
+		mockBody         string
+		mockFirstErr     []any
+		mockSaveErr      []any
+		mockRefundErr    []any
+		mockEventErr     []any
+		expectedRefunded int64
+		expectedStatus   int
+		expectedTxStatus string
+	}{
+		"successfully partially refunded transaction": {
+			testURL:          "/api/transactions/1/refunds",
+			mockBody:         `{"amount": "500.50", "reason": "damaged item"}`,
+			mockFirstErr:     []any{settled, nil},
+			mockSaveErr:      []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", RefundedAmount: 50050, Status: "partially_refunded", Version: model.Version{Version: 2}}, nil},
+			mockRefundErr:    []any{&model.Refund{ID: 1, TransactionID: 1, Amount: 50050, Currency: "IDR", Reason: "damaged item", Actor: "anonymous"}, nil},
+			mockEventErr:     []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedRefunded: 50050,
+			expectedStatus:   http.StatusCreated,
+			expectedTxStatus: "partially_refunded",
+		},
+		"successfully refunded the rest of a transaction": {
+			testURL:          "/api/transactions/1/refunds",
+			ifMatch:          `"2"`,
+			mockBody:         `{"amount": 500, "reason": "order cancelled"}`,
+			mockFirstErr:     []any{partial, nil},
+			mockSaveErr:      []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", RefundedAmount: 150000, Status: "refunded", Version: model.Version{Version: 3}}, nil},
+			mockRefundErr:    []any{&model.Refund{ID: 2, TransactionID: 1, Amount: 50000, Currency: "IDR", Reason: "order cancelled", Actor: "anonymous"}, nil},
+			mockEventErr:     []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedRefunded: 150000,
+			expectedStatus:   http.StatusCreated,
+			expectedTxStatus: "refunded",
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error missing reason": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "  "}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error if-match does not match the current version": {
+			testURL:        "/api/transactions/1/refunds",
+			ifMatch:        `"1"`,
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{partial, nil},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error transaction is not refundable": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", Status: "pending"}, nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error amount is not positive": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 0, "reason": "damaged item"}`,
+			mockFirstErr:   []any{settled, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error refunds exceed the amount": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": "500.01", "reason": "damaged item"}`,
+			mockFirstErr:   []any{partial, nil},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error version conflict": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{settled, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), &repository.ConflictError{Version: 1}},
+			expectedStatus: http.StatusConflict,
+		},
+		"error cannot save refund into database": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{settled, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", RefundedAmount: 100, Status: "partially_refunded"}, nil},
+			mockRefundErr:  []any{(*model.Refund)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot record refunded event": {
+			testURL:        "/api/transactions/1/refunds",
+			mockBody:       `{"amount": 1, "reason": "damaged item"}`,
+			mockFirstErr:   []any{settled, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Amount: 150000, Currency: "IDR", RefundedAmount: 100, Status: "partially_refunded"}, nil},
+			mockRefundErr:  []any{&model.Refund{ID: 1}, nil},
+			mockEventErr:   []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+			mockRefundRepo := new(mocks.MockDatabaseRepository[model.Refund])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				mockRefundRepo,
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+			mockRefundRepo.On("Create", mock.Anything, mock.Anything).Return(test.mockRefundErr...).Once()
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == model.EventRefunded
+			})).Return(test.mockEventErr...).Once()
+
+			router := setUpRouter()
+			router.POST("/api/transactions/:id/refunds", controller.CreateRefund)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, test.testURL, strings.NewReader(test.mockBody))
+			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusCreated {
+				mockTransactionRepo.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					return transaction.RefundedAmount == test.expectedRefunded && transaction.Status == test.expectedTxStatus
+				}))
+				mockLedger.AssertCalled(t, "Post", mock.Anything, mock.MatchedBy(func(journals []ledger.Journal) bool {
+					return len(journals) == 1 && journals[0].Type == ledger.JournalRefund && journals[0].TransactionID == 1
+				}))
+
+				var res struct {
+					Data dto.TransactionRefundResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, test.expectedTxStatus, res.Data.Transaction.Status)
+				assert.Equal(t, uint(1), res.Data.Refund.TransactionID)
+			}
+		})
+	}
+}
+
+func TestGetTransactionRefunds(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		mockFirstErr   []any
+		mockFindErr    []any
+		expectedStatus int
+		expectedCount  int
+	}{
+		"successfully get transaction refunds": {
+			testURL:      "/api/transactions/1/refunds",
+			mockFirstErr: []any{&model.Transaction{ID: 1, Status: "partially_refunded"}, nil},
+			mockFindErr: []any{[]model.Refund{
+				{ID: 1, TransactionID: 1, Amount: 50000, Currency: "IDR", Reason: "damaged item"},
+				{ID: 2, TransactionID: 1, Amount: 25000, Currency: "IDR", Reason: "late delivery"},
+			}, nil},
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format/refunds",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/1/refunds",
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error refund internal server error": {
+			testURL:        "/api/transactions/1/refunds",
+			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
+			mockFindErr:    []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+			mockRefundRepo := new(mocks.MockDatabaseRepository[model.Refund])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				mockRefundRepo,
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockRefundRepo.On("Find", mock.Anything, repository.Filter{
+				Where: repository.Eq("transaction_id", uint(1)),
+				Sort:  []repository.Sort{{Field: "id"}},
+			}).Return(test.mockFindErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions/:id/refunds", controller.GetTransactionRefunds)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusOK {
+				var res struct {
+					Data []dto.RefundResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, test.expectedCount, len(res.Data))
+			}
+		})
+	}
+}
+
+func TestGetTransactionHistory(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	eventsFilter := repository.Filter{
+		Where: repository.Eq("transaction_id", uint(1)),
+		Sort:  []repository.Sort{{Field: "id"}},
+	}
+
+	testCases := map[string]struct {
+		testURL           string
+		mockFindErr       []any
+		mockFirstErr      []any
+		expectedStatus    int
+		expectedEvents    int
+		expectedRequestID string
+	}{
+		"successfully get transaction history": {
+			testURL: "/api/transactions/1/history",
+			mockFindErr: []any{[]model.TransactionEvent{
+				{ID: 1, TransactionID: 1, Type: model.EventCreated, NewValues: model.JSON(`{"status":"pending"}`), Actor: "anonymous", RequestID: "req-1", CreatedAt: createdAt},
+				{ID: 2, TransactionID: 1, Type: model.EventStatusChanged, OldValues: model.JSON(`{"status":"pending"}`), NewValues: model.JSON(`{"status":"success"}`), Actor: "ops", RequestID: "req-2", CreatedAt: createdAt},
+			}, nil},
+			expectedStatus:    http.StatusOK,
+			expectedEvents:    2,
+			expectedRequestID: "trace-123",
+		},
+		"successfully get empty history of transaction created before it was recorded": {
+			testURL:        "/api/transactions/1/history",
+			mockFindErr:    []any{[]model.TransactionEvent{}, nil},
+			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format/history",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/1/history",
+			mockFindErr:    []any{[]model.TransactionEvent{}, nil},
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error events internal server error": {
+			testURL:        "/api/transactions/1/history",
+			mockFindErr:    []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockEventRepo.On("Find", mock.Anything, eventsFilter).Return(test.mockFindErr...).Once()
+			mockTransactionRepo.On("First", mock.Anything, uint(1)).Return(test.mockFirstErr...).Once()
+
+			router := setUpRouter()
+			router.Use(middleware.RequestID())
+			router.GET("/api/transactions/:id/history", controller.GetTransactionHistory)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+			if test.expectedRequestID != "" {
+				req.Header.Set(middleware.RequestIDHeader, test.expectedRequestID)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.NotEqual(t, "", w.Header().Get(middleware.RequestIDHeader))
+			if test.expectedRequestID != "" {
+				assert.Equal(t, test.expectedRequestID, w.Header().Get(middleware.RequestIDHeader))
+			}
+			if test.expectedStatus == http.StatusOK {
+				var body struct {
+					Data []dto.TransactionEventResponse `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &body)
+				assert.Equal(t, test.expectedEvents, len(body.Data))
+			}
+		})
+	}
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		ifMatch        string
+		mockFirstErr   []any
+		mockSaveErr    []any
+		mockEventErr   []any
+		expectedStatus int
+	}{
+		"successfully deleted transaction": {
+			testURL: "/api/transactions/1",
+			mockFirstErr: []any{&model.Transaction{
+				ID:          1,
+				UserID:      1,
+				Amount:      1000,
+				Currency:    "IDR",
+				Status:      model.StatusPending,
+				Description: "order 42",
+				Metadata:    model.Metadata{"orderId": "42"},
+				Tags:        model.Tags{"promo"},
+				CreatedAt:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedAt:   time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+				Version:     model.Version{Version: 3},
+			}, nil},
+			mockSaveErr: []any{&model.Transaction{
+				SoftDelete: model.SoftDelete{IsDeleted: true},
+			}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error transaction not found": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error transaction internal server error": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{(*model.Transaction)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot updated transaction status into database": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"successfully deleted the version the client read": {
+			testURL:        "/api/transactions/1",
+			ifMatch:        `"1"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 1}}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, SoftDelete: model.SoftDelete{IsDeleted: true}, Version: model.Version{Version: 2}}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error if-match does not match the current version": {
+			testURL:        "/api/transactions/1",
+			ifMatch:        `"2"`,
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 1}}, nil},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error transaction changed while deleting": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Version: model.Version{Version: 1}}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), &repository.ConflictError{Version: 1}},
+			expectedStatus: http.StatusConflict,
+		},
+		"successfully deleted paid transaction": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{&model.Transaction{ID: 1, Status: model.StatusSuccess}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Status: model.StatusSuccess, SoftDelete: model.SoftDelete{IsDeleted: true}}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error cannot record deleted event": {
+			testURL:        "/api/transactions/1",
+			mockFirstErr:   []any{&model.Transaction{ID: 1}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, SoftDelete: model.SoftDelete{IsDeleted: true}}, nil},
+			mockEventErr:   []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == model.EventDeleted && event.Actor == "anonymous"
+			})).Return(test.mockEventErr...)
+
+			router := setUpRouter()
+			router.DELETE("/api/transactions/:id", controller.DeleteTransaction)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodDelete, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.mockSaveErr == nil {
+				mockTransactionRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+			}
+			if test.expectedStatus == http.StatusOK {
+				loaded := *test.mockFirstErr[0].(*model.Transaction)
+				mockTransactionRepo.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					// the loaded row is saved as is apart from being flagged as deleted
+					kept := *transaction
+					kept.SoftDelete = model.SoftDelete{}
+					return transaction.IsDeleted && transaction.DeletedAt != nil && reflect.DeepEqual(loaded, kept)
+				}))
+			}
+		})
+	}
+}
+
+func TestGetDeletedTransactions(t *testing.T) {
+	deletedAt := time.Now()
+
+	testCases := map[string]struct {
+		testURL        string
+		mockCountErr   []any
+		mockFindErr    []any
+		expectedStatus int
+	}{
+		"successfully get deleted transactions": {
+			testURL:      "/api/transactions/deleted?sort=-deletedAt",
+			mockCountErr: []any{int64(1), nil},
+			mockFindErr: []any{[]model.Transaction{
+				{ID: 1, UserID: 1, Amount: 1, Status: "success", CreatedAt: time.Now(), SoftDelete: model.Deleted(deletedAt)},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid query": {
+			testURL:        "/api/transactions/deleted?sort=qwer",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error transaction internal server error": {
+			testURL:        "/api/transactions/deleted",
+			mockCountErr:   []any{int64(0), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("Count", mock.Anything, mock.Anything).Return(test.mockCountErr...).Once()
+			mockTransactionRepo.On("Find", mock.Anything, mock.Anything).Return(test.mockFindErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions/deleted", controller.GetDeletedTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusOK {
+				var res struct {
+					Data dto.Pagination[dto.TransactionResponse] `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, 1, len(res.Data.Data))
+				assert.Equal(t, deletedAt.Unix(), res.Data.Data[0].DeletedAt.Unix())
+			}
+		})
+	}
+}
+
+func TestRestoreTransaction(t *testing.T) {
+	deleted := &model.Transaction{
+		ID:          1,
+		UserID:      1,
+		Amount:      1000,
+		Currency:    "IDR",
+		Status:      "success",
+		Description: "order 42",
+		Metadata:    model.Metadata{"orderId": "42"},
+		Tags:        model.Tags{"promo"},
+		CreatedAt:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		SoftDelete:  model.Deleted(time.Now()),
+		Version:     model.Version{Version: 2},
+	}
+
+	testCases := map[string]struct {
+		testURL        string
+		ifMatch        string
+		mockFirstErr   []any
+		mockUserErr    []any
+		mockSaveErr    []any
+		mockEventErr   []any
+		expectedStatus int
+	}{
+		"successfully restored transaction": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{deleted, nil},
+			mockUserErr:    []any{&model.User{ID: 1}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Status: "success", Version: model.Version{Version: 3}}, nil},
+			mockEventErr:   []any{&model.TransactionEvent{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/transactions/wrong-format/restore",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error deleted transaction not found": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{(*model.Transaction)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error if-match does not match the current version": {
+			testURL:        "/api/transactions/1/restore",
+			ifMatch:        `"1"`,
+			mockFirstErr:   []any{deleted, nil},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		"error user of the transaction was deleted": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{deleted, nil},
+			mockUserErr:    []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusConflict,
+		},
+		"error cannot check user of the transaction": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{deleted, nil},
+			mockUserErr:    []any{(*model.User)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot restore transaction into database": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{deleted, nil},
+			mockUserErr:    []any{&model.User{ID: 1}, nil},
+			mockSaveErr:    []any{(*model.Transaction)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot record restored event": {
+			testURL:        "/api/transactions/1/restore",
+			mockFirstErr:   []any{deleted, nil},
+			mockUserErr:    []any{&model.User{ID: 1}, nil},
+			mockSaveErr:    []any{&model.Transaction{ID: 1, Status: "success", Version: model.Version{Version: 3}}, nil},
+			mockEventErr:   []any{(*model.TransactionEvent)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockUserRepo.On("First", mock.Anything, uint(1)).Return(test.mockUserErr...)
+			mockTransactionRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+
+			mockEventRepo.On("Create", mock.Anything, mock.MatchedBy(func(event *model.TransactionEvent) bool {
+				return event.Type == model.EventRestored && event.Actor == "anonymous"
+			})).Return(test.mockEventErr...)
+
+			router := setUpRouter()
+			router.POST("/api/transactions/:id/restore", controller.RestoreTransaction)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.mockUserErr != nil && test.mockUserErr[1] != nil {
+				mockTransactionRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+			}
+			if test.expectedStatus == http.StatusOK {
+				mockTransactionRepo.AssertCalled(t, "Save", mock.Anything, mock.MatchedBy(func(transaction *model.Transaction) bool {
+					// the loaded row is saved as is apart from its deleted flag and update time
+					kept := *transaction
+					kept.SoftDelete = deleted.SoftDelete
+					kept.UpdatedAt = deleted.UpdatedAt
+					return !transaction.IsDeleted && transaction.DeletedAt == nil && reflect.DeepEqual(*deleted, kept)
+				}))
+			}
+		})
+	}
+}
+
+func TestPurgeTransactions(t *testing.T) {
+	testCases := map[string]struct {
+		testURL           string
+		mockPurgeErr      []any
+		expectedRetention time.Duration
+		expectedStatus    int
+	}{
+		"successfully purged transactions past the retention": {
+			testURL:           "/api/transactions/purge",
+			mockPurgeErr:      []any{int64(3), nil},
+			expectedRetention: 720 * time.Hour,
+			expectedStatus:    http.StatusOK,
+		},
+		"successfully purged transactions older than the query": {
+			testURL:           "/api/transactions/purge?olderThan=24h",
+			mockPurgeErr:      []any{int64(0), nil},
+			expectedRetention: 24 * time.Hour,
+			expectedStatus:    http.StatusOK,
+		},
+		"error invalid older than": {
+			testURL:        "/api/transactions/purge?olderThan=a-week",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error negative older than": {
+			testURL:        "/api/transactions/purge?olderThan=-1h",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error cannot purge transactions from database": {
+			testURL:           "/api/transactions/purge",
+			mockPurgeErr:      []any{int64(0), errors.New("")},
+			expectedRetention: 720 * time.Hour,
+			expectedStatus:    http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("Purge", mock.Anything, mock.MatchedBy(func(deletedBefore time.Time) bool {
+				age := time.Since(deletedBefore)
+				return age >= test.expectedRetention && age < test.expectedRetention+time.Minute
+			}), repository.Filter{Where: repository.NotIn("status", model.SettledStatuses())}).Return(test.mockPurgeErr...).Once()
+
+			router := setUpRouter()
+			router.POST("/api/transactions/purge", controller.PurgeTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestExportTransactions(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	transactions := []model.Transaction{
+		{ID: 1, UserID: 1, Amount: 1050, Currency: "USD", Status: "success", CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: 2, UserID: 2, Amount: 300, Currency: "USD", Status: "pending", CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+
+	testCases := map[string]struct {
+		testURL             string
+		mockEachErr         []any
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		"successfully exported transactions as csv": {
+			testURL:             "/api/transactions/export?columns=id,amount,status,createdAt",
+			mockEachErr:         []any{transactions, nil},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "id,amount,status,createdAt\n1,10.50,success,2024-01-02T03:04:05Z\n2,3.00,pending,2024-01-02T03:04:05Z\n",
+		},
+		"successfully exported transactions as ndjson in a time zone": {
+			testURL:             "/api/transactions/export?format=ndjson&columns=id,createdAt&tz=Asia/Jakarta",
+			mockEachErr:         []any{transactions[:1], nil},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        `{"createdAt":"2024-01-02T10:04:05+07:00","id":1}` + "\n",
+		},
+		"successfully exported no transactions": {
+			testURL:             "/api/transactions/export?columns=id,status",
+			mockEachErr:         []any{[]model.Transaction{}, nil},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "id,status\n",
+		},
+		"error invalid format": {
+			testURL:        "/api/transactions/export?format=xlsx",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid column": {
+			testURL:        "/api/transactions/export?columns=id,password",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid time zone": {
+			testURL:        "/api/transactions/export?tz=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error server time zone": {
+			testURL:        "/api/transactions/export?tz=Local",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error invalid query": {
+			testURL:        "/api/transactions/export?sort=qwer",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error transaction internal server error": {
+			testURL:        "/api/transactions/export",
+			mockEachErr:    []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockTransactionRepo.On("Each", mock.Anything, mock.Anything).Return(test.mockEachErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/transactions/export", middleware.NoTimeout(), controller.ExportTransactions)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusOK {
+				assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+				assert.Equal(t, test.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTransactionErrorBody(t *testing.T) {
+	testCases := map[string]struct {
+		mockBody            any
+		accept              string
+		mockFirstErr        []any
+		expectedStatus      int
+		expectedContentType string
+		expectedCode        string
+		expectedField       string
+	}{
+		"validation error with field details": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "qwer",
+			},
+			expectedStatus:      http.StatusUnprocessableEntity,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedCode:        apperror.CodeValidationFailed,
+			expectedField:       "status",
+		},
+		"validation error on a too precise amount": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1.005",
+				Currency: "USD",
+				Status:   "pending",
+			},
+			expectedStatus:      http.StatusUnprocessableEntity,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedCode:        apperror.CodeValidationFailed,
+			expectedField:       "amount",
+		},
+		"internal error without database details": {
+			mockBody: &dto.TransactionCreate{
+				UserID:   1,
+				Amount:   "1",
+				Currency: "IDR",
+				Status:   "pending",
+			},
+			mockFirstErr:        []any{(*model.User)(nil), errors.New("relation \"users\" does not exist")},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedCode:        apperror.CodeInternal,
+		},
+		"problem details when accepted": {
+			mockBody:            "wrong-format",
+			accept:              "application/problem+json",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedCode:        apperror.CodeInvalidJSON,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockEventRepo := new(mocks.MockDatabaseRepository[model.TransactionEvent])
+
+			mockLedger := new(mocks.MockLedger)
+			mockLedger.On("Post", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := transactioncontroller.NewTransactionController(
+				mockTransactionRepo,
+				mockUserRepo,
+				mockEventRepo,
+				new(mocks.MockDatabaseRepository[model.Refund]),
+				new(mocks.MockTransactor),
+				mockLedger,
+				720*time.Hour,
+			)
+
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+
+			router := setUpRouter()
+			router.POST("/api/transactions", controller.CreateTransaction)
+
+			w := httptest.NewRecorder()
+
+			body, _ := json.Marshal(test.mockBody)
+			req, _ := http.NewRequest(http.MethodPost, "/api/transactions", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", test.accept)
+
+			router.ServeHTTP(w, req)
+
+			var res struct {
+				Code   string                `json:"code"`
+				Errors []apperror.FieldError `json:"errors"`
+			}
+			_ = json.Unmarshal(w.Body.Bytes(), &res)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.expectedCode, res.Code)
+			assert.Equal(t, false, bytes.Contains(w.Body.Bytes(), []byte("relation")))
+			if test.expectedField != "" {
+				assert.Equal(t, test.expectedField, res.Errors[0].Field)
+			}
 		})
 	}
 }
@@ -0,0 +1,128 @@
+package transactioncontroller
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/util"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	errDeletedTransactionNotFound = apperror.NotFound("deleted_transaction_not_found", "deleted transaction not found")
+	errRestoreUserDeleted         = apperror.Conflict("user_deleted", "the user of the transaction was deleted, it cannot be restored")
+	errInvalidOlderThan           = apperror.InvalidQuery(apperror.FieldError{
+		Field:   "olderThan",
+		Code:    "invalid",
+		Message: "olderThan must be a non negative duration such as 720h",
+	})
+)
+
+// GetDeletedTransactions lists soft deleted transactions, it takes the same query as GetTransactions
+func (tc *TransactionController) GetDeletedTransactions(c *gin.Context) {
+	c.Request = c.Request.WithContext(repository.OnlyDeleted(c.Request.Context()))
+
+	tc.listTransactions(c, nil)
+}
+
+func (tc *TransactionController) RestoreTransaction(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if deleted transaction exist
+	transaction, firstErr := tc.TransactionRepo.First(repository.OnlyDeleted(c.Request.Context()), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errDeletedTransactionNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// check if the client restores the version it read
+	if !util.IfMatch(c, util.ETag(transaction.Version.Version)) {
+		util.Error(c, errPreconditionFailed)
+		return
+	}
+
+	// restore transaction and save it to database along with its restored event
+	restored := *transaction
+	restored.SoftDelete = model.SoftDelete{}
+	restored.UpdatedAt = time.Now()
+
+	var restoredTransaction *model.Transaction
+	saveErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		// check if user still exist, keeping it from being deleted until the transaction is committed
+		if _, err := tc.UserRepo.First(repository.ForKeyShare(ctx), transaction.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRestoreUserDeleted
+			}
+
+			return err
+		}
+
+		var err error
+		restoredTransaction, err = tc.TransactionRepo.Save(ctx, &restored, id)
+		if err != nil {
+			return err
+		}
+
+		return tc.recordEvent(c, ctx, model.EventRestored, transaction, restoredTransaction)
+	})
+	if saveErr != nil {
+		util.Error(c, saveConflict(c, saveErr))
+		return
+	}
+
+	// build response
+	c.Header("ETag", util.ETag(restoredTransaction.Version.Version))
+	res := dto.NewTransactionResponse(*restoredTransaction)
+
+	// return response
+	util.Success(c, "transaction restored successfully", res)
+}
+
+// PurgeTransactions permanently removes transactions deleted longer than olderThan ago, along with their
+// history. olderThan defaults to DeletedRetention, settled transactions are never purged
+func (tc *TransactionController) PurgeTransactions(c *gin.Context) {
+	// get query from context
+	retention := tc.DeletedRetention
+	if value := c.Query("olderThan"); value != "" {
+		var err error
+		retention, err = time.ParseDuration(value)
+		if err != nil || retention < 0 {
+			util.Error(c, errInvalidOlderThan)
+			return
+		}
+	}
+
+	// delete transactions from database, settled ones are kept along with the journals of their payment
+	deletedBefore := time.Now().Add(-retention)
+	purged, purgeErr := tc.TransactionRepo.Purge(c.Request.Context(), deletedBefore, repository.Filter{
+		Where: repository.NotIn("status", model.SettledStatuses()),
+	})
+	if purgeErr != nil {
+		util.Error(c, purgeErr)
+		return
+	}
+
+	// build response
+	res := dto.TransactionPurgeResponse{
+		Purged:        purged,
+		DeletedBefore: deletedBefore,
+	}
+
+	// return response
+	util.Success(c, "deleted transaction(s) purged successfully", res)
+}
@@ -0,0 +1,265 @@
+package transactioncontroller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/middleware"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/util"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+
+	// exportFlushRows is how many rows are buffered before they are sent to the client
+	exportFlushRows = 500
+)
+
+// exportColumn is a column clients can pick for an export, value is written as is to NDJSON and with its
+// default format to CSV
+type exportColumn struct {
+	name  string
+	value func(transaction *model.Transaction, location *time.Location) any
+}
+
+var exportColumns = []exportColumn{
+	{"id", func(t *model.Transaction, _ *time.Location) any { return t.ID }},
+	{"userId", func(t *model.Transaction, _ *time.Location) any { return t.UserID }},
+	{"amount", func(t *model.Transaction, _ *time.Location) any { return money.New(t.Amount, t.Currency) }},
+	{"currency", func(t *model.Transaction, _ *time.Location) any { return t.Currency }},
+	{"refundedAmount", func(t *model.Transaction, _ *time.Location) any { return money.New(t.RefundedAmount, t.Currency) }},
+	{"status", func(t *model.Transaction, _ *time.Location) any { return t.Status }},
+	{"createdAt", func(t *model.Transaction, l *time.Location) any { return t.CreatedAt.In(l).Format(time.RFC3339) }},
+	{"updatedAt", func(t *model.Transaction, l *time.Location) any { return t.UpdatedAt.In(l).Format(time.RFC3339) }},
+	{"version", func(t *model.Transaction, _ *time.Location) any { return t.Version.Version }},
+}
+
+// exportContentTypes maps the formats to the content type and file extension of their response
+var exportContentTypes = map[string][2]string{
+	ExportFormatCSV:    {"text/csv; charset=utf-8", "csv"},
+	ExportFormatNDJSON: {"application/x-ndjson", "ndjson"},
+}
+
+// ExportTransactions streams the transactions matching the listing filters as CSV or NDJSON, reading them
+// from the database while they are written instead of loading them all
+func (tc *TransactionController) ExportTransactions(c *gin.Context) {
+	// bind payload into json
+	var payload dto.ExportTransactionsQuery
+	if err := c.ShouldBindQuery(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+	payload.Metadata = metadataQuery(c)
+
+	// map payload into filters and export options
+	where, sort, filterErr := buildTransactionFilter(payload.GetTransactionsQuery)
+	if filterErr != nil {
+		util.Error(c, filterErr)
+		return
+	}
+	if len(sort) == 0 {
+		sort = []repository.Sort{{Field: "created_at"}, {Field: "id"}}
+	}
+
+	format, columns, location, optionErr := parseExportOptions(payload)
+	if optionErr != nil {
+		util.Error(c, optionErr)
+		return
+	}
+
+	// the response starts with the first row, so a query failing before it still gets an error response
+	writer := newExportWriter(c, format, columns, location)
+	eachErr := tc.TransactionRepo.Each(
+		c.Request.Context(),
+		repository.Filter{Where: where, Sort: sort},
+		writer.write,
+	)
+	if eachErr != nil && !writer.started {
+		util.Error(c, eachErr)
+		return
+	}
+	if eachErr != nil {
+		// the status is already sent, the client sees a truncated export
+		log.Printf("%s %s [%s]: export stopped: %v", c.Request.Method, c.Request.URL.Path, middleware.RequestIDFrom(c.Request.Context()), eachErr)
+		return
+	}
+
+	// return response
+	if err := writer.close(); err != nil {
+		log.Printf("%s %s [%s]: export stopped: %v", c.Request.Method, c.Request.URL.Path, middleware.RequestIDFrom(c.Request.Context()), err)
+	}
+}
+
+func parseExportOptions(query dto.ExportTransactionsQuery) (string, []exportColumn, *time.Location, error) {
+	var fields []apperror.FieldError
+
+	format := query.Format
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		fields = append(fields, apperror.FieldError{
+			Field:   "format",
+			Code:    "invalid",
+			Message: "format must be csv or ndjson",
+		})
+	}
+
+	columns := exportColumns
+	if query.Columns != "" {
+		columns = nil
+		for _, name := range strings.Split(query.Columns, ",") {
+			column, ok := findExportColumn(strings.TrimSpace(name))
+			if !ok {
+				fields = append(fields, apperror.FieldError{
+					Field:   "columns",
+					Code:    "invalid",
+					Message: fmt.Sprintf("cannot export column %q", strings.TrimSpace(name)),
+				})
+				continue
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	location := time.UTC
+	if query.Timezone != "" {
+		var err error
+		if location, err = util.LoadTimezone(query.Timezone); err != nil {
+			fields = append(fields, apperror.FieldError{
+				Field:   "tz",
+				Code:    "invalid",
+				Message: "tz must be an IANA time zone name such as Asia/Jakarta",
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return "", nil, nil, apperror.InvalidQuery(fields...)
+	}
+
+	return format, columns, location, nil
+}
+
+func findExportColumn(name string) (exportColumn, bool) {
+	for _, column := range exportColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+
+	return exportColumn{}, false
+}
+
+// exportWriter writes rows to the response, flushing them every exportFlushRows rows
+type exportWriter struct {
+	c        *gin.Context
+	format   string
+	columns  []exportColumn
+	location *time.Location
+	csv      *csv.Writer
+	started  bool
+	rows     int
+}
+
+func newExportWriter(c *gin.Context, format string, columns []exportColumn, location *time.Location) *exportWriter {
+	return &exportWriter{c: c, format: format, columns: columns, location: location}
+}
+
+// start sends the headers of the response and the header row of a CSV
+func (w *exportWriter) start() error {
+	w.started = true
+
+	contentType := exportContentTypes[w.format]
+	w.c.Header("Content-Type", contentType[0])
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions.%s"`, contentType[1]))
+	w.c.Status(200)
+
+	if w.format != ExportFormatCSV {
+		return nil
+	}
+
+	w.csv = csv.NewWriter(w.c.Writer)
+	header := make([]string, 0, len(w.columns))
+	for _, column := range w.columns {
+		header = append(header, column.name)
+	}
+
+	return w.csv.Write(header)
+}
+
+func (w *exportWriter) write(transaction *model.Transaction) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.writeRow(transaction); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushRows == 0 {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *exportWriter) writeRow(transaction *model.Transaction) error {
+	if w.format == ExportFormatCSV {
+		record := make([]string, 0, len(w.columns))
+		for _, column := range w.columns {
+			record = append(record, fmt.Sprint(column.value(transaction, w.location)))
+		}
+
+		return w.csv.Write(record)
+	}
+
+	object := make(map[string]any, len(w.columns))
+	for _, column := range w.columns {
+		object[column.name] = column.value(transaction, w.location)
+	}
+	line, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w.c.Writer, string(line)+"\n")
+	return err
+}
+
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+
+	return nil
+}
+
+// close finishes the export, an export without rows still gets its headers
+func (w *exportWriter) close() error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	return w.flush()
+}
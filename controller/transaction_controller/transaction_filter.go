@@ -0,0 +1,344 @@
+package transactioncontroller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortFields maps the sort keys accepted from clients to transaction columns
+var sortFields = map[string]string{
+	"id":        "id",
+	"amount":    "amount",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"deletedAt": "deleted_at",
+}
+
+// buildTransactionFilter turns a listing query into the where expression and sort used by the repository
+func buildTransactionFilter(query dto.GetTransactionsQuery) (repository.Expression, []repository.Sort, error) {
+	var fields []apperror.FieldError
+
+	// each criterion is matched as a whole, so a range never gets split by match=any
+	var criteria []repository.Expression
+
+	userIDs, userErr := parseUserIDs(splitValues(query.UserIDs))
+	if userErr != nil {
+		fields = append(fields, *userErr)
+	}
+	if len(userIDs) > 0 {
+		criteria = append(criteria, repository.In("user_id", userIDs))
+	}
+
+	statuses := splitValues(query.Statuses)
+	for _, status := range statuses {
+		if !model.IsValidStatus(status) {
+			fields = append(fields, apperror.FieldError{
+				Field:   "status",
+				Code:    "invalid",
+				Message: fmt.Sprintf("unknown status %q", status),
+			})
+		}
+	}
+	if len(statuses) > 0 {
+		criteria = append(criteria, repository.In("status", statuses))
+	}
+
+	currencies, currencyErrs := parseCurrencies(splitValues(query.Currencies))
+	fields = append(fields, currencyErrs...)
+	if len(currencies) > 0 {
+		criteria = append(criteria, repository.In("currency", currencies))
+	}
+
+	amount, amountErrs := amountRange(currencies, query.MinAmount, query.MaxAmount)
+	fields = append(fields, amountErrs...)
+	if amount != nil {
+		criteria = append(criteria, amount)
+	}
+
+	created, createdErrs := timeRange("created_at", "createdFrom", query.CreatedFrom, "createdTo", query.CreatedTo)
+	fields = append(fields, createdErrs...)
+	if created != nil {
+		criteria = append(criteria, created)
+	}
+
+	updated, updatedErrs := timeRange("updated_at", "updatedFrom", query.UpdatedFrom, "updatedTo", query.UpdatedTo)
+	fields = append(fields, updatedErrs...)
+	if updated != nil {
+		criteria = append(criteria, updated)
+	}
+
+	// a transaction with any of the tags matches, tags are stored in lowercase
+	var tagged []repository.Expression
+	for _, tag := range splitValues(query.Tags) {
+		tagged = append(tagged, repository.Has("tags", strings.ToLower(tag)))
+	}
+	if len(tagged) > 0 {
+		criteria = append(criteria, repository.Any(tagged...))
+	}
+
+	// metadata keys are matched in order, so the same query always builds the same filter
+	keys := make([]string, 0, len(query.Metadata))
+	for key := range query.Metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !transactionservice.MetadataKeyPattern.MatchString(key) {
+			fields = append(fields, apperror.FieldError{
+				Field:   "metadata." + key,
+				Code:    "invalid",
+				Message: fmt.Sprintf("invalid metadata key %q", key),
+			})
+			continue
+		}
+		criteria = append(criteria, repository.KeyEq("metadata", key, query.Metadata[key]))
+	}
+
+	sort, sortErr := parseSort(query.Sort)
+	if sortErr != nil {
+		fields = append(fields, *sortErr)
+	}
+
+	var where repository.Expression
+	switch query.Match {
+	case "", string(repository.MatchAll):
+		where = repository.All(criteria...)
+	case string(repository.MatchAny):
+		where = repository.Any(criteria...)
+	default:
+		fields = append(fields, apperror.FieldError{
+			Field:   "match",
+			Code:    "invalid",
+			Message: "match must be all or any",
+		})
+	}
+
+	if len(fields) > 0 {
+		return nil, nil, apperror.InvalidQuery(fields...)
+	}
+
+	return where, sort, nil
+}
+
+// metadataQuery collects the metadata.<key> query parameters of c, the last value of a repeated key is used
+func metadataQuery(c *gin.Context) map[string]string {
+	metadata := make(map[string]string)
+	for name, values := range c.Request.URL.Query() {
+		if key, ok := strings.CutPrefix(name, "metadata."); ok && len(values) > 0 {
+			metadata[key] = values[len(values)-1]
+		}
+	}
+
+	return metadata
+}
+
+// splitValues accepts both repeated and comma separated query values
+func splitValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+
+	return result
+}
+
+func parseUserIDs(values []string) ([]uint, *apperror.FieldError) {
+	var ids []uint
+	for _, value := range values {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			return nil, &apperror.FieldError{
+				Field:   "userId",
+				Code:    "invalid",
+				Message: fmt.Sprintf("invalid userId %q", value),
+			}
+		}
+		ids = append(ids, uint(id))
+	}
+
+	return ids, nil
+}
+
+// parseSort reads a comma separated list of fields, a leading "-" sorts the field descending
+func parseSort(value string) ([]repository.Sort, *apperror.FieldError) {
+	var sort []repository.Sort
+	hasID := false
+	for _, field := range splitValues([]string{value}) {
+		descending := strings.HasPrefix(field, "-")
+		column, ok := sortFields[strings.TrimPrefix(field, "-")]
+		if !ok {
+			return nil, &apperror.FieldError{
+				Field:   "sort",
+				Code:    "invalid",
+				Message: fmt.Sprintf("cannot sort by %q", strings.TrimPrefix(field, "-")),
+			}
+		}
+
+		hasID = hasID || column == "id"
+		sort = append(sort, repository.Sort{Field: column, Descending: descending})
+	}
+
+	// id breaks ties so pages never overlap
+	if len(sort) > 0 && !hasID {
+		sort = append(sort, repository.Sort{Field: "id"})
+	}
+
+	return sort, nil
+}
+
+func parseCurrencies(values []string) ([]string, []apperror.FieldError) {
+	var fields []apperror.FieldError
+	currencies := make([]string, 0, len(values))
+	for _, value := range values {
+		currency, err := money.Lookup(value)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{
+				Field:   "currency",
+				Code:    "invalid",
+				Message: fmt.Sprintf("unknown currency %q", value),
+			})
+			continue
+		}
+		currencies = append(currencies, currency.Code)
+	}
+
+	return currencies, fields
+}
+
+// amountRange reads minAmount and maxAmount as decimals of the single currency being filtered on,
+// amounts of different currencies cannot be compared
+func amountRange(currencies []string, min string, max string) (repository.Expression, []apperror.FieldError) {
+	if min == "" && max == "" {
+		return nil, nil
+	}
+	if len(currencies) != 1 {
+		return nil, []apperror.FieldError{{
+			Field:   "currency",
+			Code:    "required",
+			Message: "minAmount and maxAmount need exactly one currency",
+		}}
+	}
+	currency, _ := money.Lookup(currencies[0])
+
+	var fields []apperror.FieldError
+	var amount []repository.Expression
+	var minMinor, maxMinor int64
+	if min != "" {
+		var err error
+		if minMinor, err = currency.Parse(min); err != nil {
+			fields = append(fields, invalidAmount("minAmount", currency))
+		} else {
+			amount = append(amount, repository.Gte("amount", minMinor))
+		}
+	}
+	if max != "" {
+		var err error
+		if maxMinor, err = currency.Parse(max); err != nil {
+			fields = append(fields, invalidAmount("maxAmount", currency))
+		} else {
+			amount = append(amount, repository.Lte("amount", maxMinor))
+		}
+	}
+	if len(fields) == 0 && min != "" && max != "" && minMinor > maxMinor {
+		fields = append(fields, apperror.FieldError{
+			Field:   "minAmount",
+			Code:    "invalid_range",
+			Message: "minAmount must not be greater than maxAmount",
+		})
+	}
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	return repository.All(amount...), nil
+}
+
+func invalidAmount(name string, currency money.Currency) apperror.FieldError {
+	return apperror.FieldError{
+		Field:   name,
+		Code:    "invalid_amount",
+		Message: fmt.Sprintf("%s must be a decimal with at most %d decimals in %s", name, currency.Exponent, currency.Code),
+	}
+}
+
+// timeRange accepts RFC 3339 timestamps or dates, a date as upper bound includes that whole day
+func timeRange(column string, fromName string, from string, toName string, to string) (repository.Expression, []apperror.FieldError) {
+	var fields []apperror.FieldError
+	var bounds []repository.Expression
+	var fromTime, toTime time.Time
+
+	if from != "" {
+		t, _, err := parseTime(from)
+		if err != nil {
+			fields = append(fields, invalidTime(fromName))
+		}
+
+		fromTime = t
+		bounds = append(bounds, repository.Gte(column, t))
+	}
+
+	if to != "" {
+		t, isDate, err := parseTime(to)
+		if err != nil {
+			fields = append(fields, invalidTime(toName))
+		}
+
+		toTime = t
+		if isDate {
+			bounds = append(bounds, repository.Lt(column, t.AddDate(0, 0, 1)))
+		} else {
+			bounds = append(bounds, repository.Lte(column, t))
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	if from != "" && to != "" && fromTime.After(toTime) {
+		return nil, []apperror.FieldError{{
+			Field:   fromName,
+			Code:    "invalid_range",
+			Message: fmt.Sprintf("%s must not be after %s", fromName, toName),
+		}}
+	}
+
+	if len(bounds) == 0 {
+		return nil, nil
+	}
+
+	return repository.All(bounds...), nil
+}
+
+func invalidTime(name string) apperror.FieldError {
+	return apperror.FieldError{
+		Field:   name,
+		Code:    "invalid_time",
+		Message: fmt.Sprintf("%s must be a RFC 3339 timestamp or a date", name),
+	}
+}
+
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+
+	return t, true, err
+}
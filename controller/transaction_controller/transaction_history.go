@@ -0,0 +1,82 @@
+package transactioncontroller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/middleware"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"gorm.io/gorm"
+)
+
+func (tc *TransactionController) GetTransactionHistory(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// find events of the transaction, deleted transactions keep their history
+	events, findErr := tc.EventRepo.Find(c.Request.Context(), repository.Filter{
+		Where: repository.Eq("transaction_id", id),
+		Sort:  []repository.Sort{{Field: "id"}},
+	})
+	if findErr != nil {
+		util.Error(c, findErr)
+		return
+	}
+
+	// check if transaction exist, it may predate the history
+	if len(events) == 0 {
+		_, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
+		if firstErr != nil {
+			if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+				util.Error(c, errTransactionNotFound)
+				return
+			}
+
+			util.Error(c, firstErr)
+			return
+		}
+	}
+
+	// build response
+	res := make([]dto.TransactionEventResponse, 0, len(events))
+	for _, event := range events {
+		res = append(res, dto.TransactionEventResponse{
+			ID:        event.ID,
+			Type:      event.Type,
+			OldValues: json.RawMessage(event.OldValues),
+			NewValues: json.RawMessage(event.NewValues),
+			Actor:     event.Actor,
+			RequestID: event.RequestID,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	// return response
+	util.Success(c, "transaction history fetched successfully", res)
+}
+
+// recordEvent stores a change of a transaction, it must run in the same database transaction as the change
+func (tc *TransactionController) recordEvent(c *gin.Context, ctx context.Context, eventType string, before *model.Transaction, after *model.Transaction) error {
+	event, err := newEvent(c, eventType, before, after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tc.EventRepo.Create(ctx, event)
+
+	return err
+}
+
+// newEvent describes a change of a transaction made by the request of c
+func newEvent(c *gin.Context, eventType string, before *model.Transaction, after *model.Transaction) (*model.TransactionEvent, error) {
+	return transactionservice.NewEvent(util.Actor(c), middleware.RequestIDFrom(c.Request.Context()), eventType, before, after)
+}
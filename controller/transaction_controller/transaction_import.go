@@ -0,0 +1,143 @@
+package transactioncontroller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/middleware"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	importFileField = "file"
+	importReportCSV = "csv"
+)
+
+var (
+	errUnsupportedImport = apperror.New(
+		http.StatusUnsupportedMediaType,
+		"unsupported_media_type",
+		"content type must be text/csv or multipart/form-data with a "+importFileField+" field",
+	)
+	errInvalidDryRun = apperror.InvalidQuery(apperror.FieldError{
+		Field:   "dryRun",
+		Code:    "invalid",
+		Message: "dryRun must be true or false",
+	})
+	errInvalidReport = apperror.InvalidQuery(apperror.FieldError{
+		Field:   "report",
+		Code:    "invalid",
+		Message: "report must be csv",
+	})
+)
+
+// ImportTransactions imports transactions from a csv with a header row, sent as the body or as the file field of
+// a form. Rows are validated like CreateTransaction and the rows that failed are reported, as json or as a csv
+// to download when report=csv
+func (tc *TransactionController) ImportTransactions(c *gin.Context) {
+	// get query from context
+	dryRun, dryRunErr := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if dryRunErr != nil {
+		util.Error(c, errInvalidDryRun)
+		return
+	}
+	report := c.Query("report")
+	if report != "" && report != importReportCSV {
+		util.Error(c, errInvalidReport)
+		return
+	}
+
+	// read payload
+	body, bodyErr := importBody(c)
+	if bodyErr != nil {
+		util.Error(c, bodyErr)
+		return
+	}
+	defer body.Close()
+
+	// import transactions into database
+	result, importErr := tc.Service.Import(c.Request.Context(), body, transactionservice.ImportOptions{
+		Columns:   c.QueryMap("column"),
+		DryRun:    dryRun,
+		Actor:     util.Actor(c),
+		RequestID: middleware.RequestIDFrom(c.Request.Context()),
+	})
+	if importErr != nil {
+		util.Error(c, importErr)
+		return
+	}
+
+	// build response
+	status, message := http.StatusCreated, "transaction(s) imported successfully"
+	switch {
+	case result.DryRun:
+		status, message = http.StatusOK, "import checked, nothing imported"
+	case result.Imported == 0:
+		status, message = http.StatusUnprocessableEntity, "no transaction imported"
+	case len(result.Errors) > 0:
+		status, message = http.StatusMultiStatus, "some row(s) could not be imported"
+	}
+
+	// return response
+	if report == importReportCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="transactions-import-errors.csv"`)
+		c.Status(status)
+		if err := result.WriteReport(c.Writer); err != nil {
+			log.Printf("%s %s [%s]: import report stopped: %v", c.Request.Method, c.Request.URL.Path, middleware.RequestIDFrom(c.Request.Context()), err)
+		}
+		return
+	}
+	util.SendResponse(c, status, buildImportResponse(result), message)
+}
+
+// importBody opens the csv of the request, bounded by transactionservice.MaxImportBytes
+func importBody(c *gin.Context) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, transactionservice.MaxImportBytes)
+
+	switch c.ContentType() {
+	case "multipart/form-data":
+		header, err := c.FormFile(importFileField)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, transactionservice.ErrImportTooLarge
+			}
+
+			return nil, apperror.BadRequest(apperror.CodeInvalidRequest, "form has no "+importFileField+" field").Wrap(err)
+		}
+
+		return header.Open()
+	case "text/csv", "application/csv", "text/plain", "":
+		return c.Request.Body, nil
+	}
+
+	return nil, errUnsupportedImport
+}
+
+func buildImportResponse(result *transactionservice.ImportResult) dto.TransactionImportResponse {
+	res := dto.TransactionImportResponse{
+		DryRun:   result.DryRun,
+		Rows:     result.Rows,
+		Valid:    result.Valid,
+		Imported: result.Imported,
+		Failed:   len(result.Errors),
+		Errors:   make([]dto.TransactionImportRowError, 0, len(result.Errors)),
+	}
+
+	for _, rowErr := range result.Errors {
+		appErr := apperror.From(rowErr.Err)
+		res.Errors = append(res.Errors, dto.TransactionImportRowError{
+			Line:          rowErr.Line,
+			BulkItemError: dto.BulkItemError{Code: appErr.Code, Message: appErr.Message, Fields: appErr.Fields},
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,239 @@
+package transactioncontroller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/patch"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"time"
+)
+
+var (
+	errUnsupportedPatch = apperror.New(
+		http.StatusUnsupportedMediaType,
+		"unsupported_media_type",
+		"content type must be "+patch.MergePatchContentType+" or "+patch.JSONPatchContentType,
+	)
+	errAmountLocked = apperror.Conflict("amount_locked", "amount and currency can only change while the transaction is pending")
+)
+
+// optionalPatchMembers can be removed by a patch, the others are required
+var optionalPatchMembers = map[string]bool{"description": true, "metadata": true, "tags": true}
+
+// PatchTransaction changes the fields of dto.TransactionPatch with an RFC 7396 merge patch, or an RFC 6902 JSON
+// patch when sent as application/json-patch+json. The patched transaction is validated like a created one
+func (tc *TransactionController) PatchTransaction(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// pick the patch format from the content type
+	var apply func(document []byte, patch []byte) ([]byte, error)
+	switch c.ContentType() {
+	case patch.MergePatchContentType, "application/json", "":
+		apply = patch.Merge
+	case patch.JSONPatchContentType:
+		apply = patch.Apply
+	default:
+		util.Error(c, errUnsupportedPatch)
+		return
+	}
+
+	// read payload
+	body, bodyErr := c.GetRawData()
+	if bodyErr != nil {
+		util.Error(c, apperror.FromBinding(bodyErr))
+		return
+	}
+
+	// check if transaction exist
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errTransactionNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// check if the client changes the version it read
+	if !util.IfMatch(c, util.ETag(transaction.Version.Version)) {
+		util.Error(c, errPreconditionFailed)
+		return
+	}
+
+	// apply the patch to the patchable fields of the transaction
+	payload, patchErr := patchDocument(*transaction, body, apply)
+	if patchErr != nil {
+		util.Error(c, patchErr)
+		return
+	}
+
+	// validate status
+	if !model.IsValidStatus(payload.Status) {
+		util.Error(c, transactionservice.ErrInvalidStatus)
+		return
+	}
+
+	// validate amount in the minor unit of its currency
+	amount, currency, amountErr := transactionservice.ParseAmount(payload.Amount, payload.Currency)
+	if amountErr != nil {
+		util.Error(c, amountErr)
+		return
+	}
+
+	// validate description, metadata and tags
+	annotated, annotationErr := transactionservice.ParseAnnotations(payload.Description, payload.Metadata, payload.Tags)
+	if annotationErr != nil {
+		util.Error(c, annotationErr)
+		return
+	}
+
+	// a patch that changes nothing is not saved
+	amountChanged := amount != transaction.Amount || currency.Code != transaction.Currency
+	statusChanged := payload.Status != transaction.Status
+	annotationsChanged := !annotated.Equal(*transaction)
+	if !amountChanged && !statusChanged && !annotationsChanged {
+		c.Header("ETag", util.ETag(transaction.Version.Version))
+		util.Success(c, "transaction updated successfully", dto.NewTransactionResponse(*transaction))
+		return
+	}
+
+	// check if the amount may change
+	if amountChanged && transaction.Status != model.StatusPending {
+		util.Error(c, errAmountLocked)
+		return
+	}
+
+	// check if the status may change
+	if statusChanged && !model.CanTransition(transaction.Status, payload.Status) {
+		util.Error(c, illegalTransition(transaction.Status, payload.Status))
+		return
+	}
+
+	// status only patches keep the status changed event that PUT records
+	eventType := model.EventUpdated
+	if !amountChanged && !annotationsChanged {
+		eventType = model.EventStatusChanged
+	}
+
+	// update transaction and save it to database along with its event and, on success, its payment journal
+	updated := *transaction
+	updated.Amount = amount
+	updated.Currency = currency.Code
+	updated.Status = payload.Status
+	updated.Description = annotated.Description
+	updated.Metadata = annotated.Metadata
+	updated.Tags = annotated.Tags
+	updated.UpdatedAt = time.Now()
+
+	var updatedTransaction *model.Transaction
+	saveErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		var err error
+		updatedTransaction, err = tc.TransactionRepo.Save(ctx, &updated, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tc.recordEvent(c, ctx, eventType, transaction, updatedTransaction); err != nil {
+			return err
+		}
+
+		return tc.Service.PostPayments(ctx, []model.Transaction{*transaction}, []model.Transaction{*updatedTransaction})
+	})
+	if saveErr != nil {
+		util.Error(c, saveConflict(c, saveErr))
+		return
+	}
+
+	// build response
+	c.Header("ETag", util.ETag(updatedTransaction.Version.Version))
+	res := dto.NewTransactionResponse(*updatedTransaction)
+
+	// return response
+	util.Success(c, "transaction updated successfully", res)
+}
+
+// patchDocument applies body to the dto.TransactionPatch of transaction. Members outside of it cannot be added
+// and only the optional description, metadata and tags can be removed, which clears them
+func patchDocument(transaction model.Transaction, body []byte, apply func([]byte, []byte) ([]byte, error)) (dto.TransactionPatch, error) {
+	var payload dto.TransactionPatch
+
+	// the document always has an object and a list, so a JSON patch can add to them
+	metadata, err := json.Marshal(transaction.Metadata)
+	if err != nil {
+		return payload, err
+	}
+	if transaction.Metadata == nil {
+		metadata = json.RawMessage(`{}`)
+	}
+	tags := transaction.Tags
+	if tags == nil {
+		tags = model.Tags{}
+	}
+	document, err := json.Marshal(dto.TransactionPatch{
+		Amount:      json.Number(money.New(transaction.Amount, transaction.Currency).String()),
+		Currency:    transaction.Currency,
+		Status:      transaction.Status,
+		Description: transaction.Description,
+		Metadata:    metadata,
+		Tags:        tags,
+	})
+	if err != nil {
+		return payload, err
+	}
+
+	patched, err := apply(document, body)
+	switch {
+	case errors.Is(err, patch.ErrConflict):
+		return payload, apperror.Conflict("patch_conflict", err.Error()).Wrap(err)
+	case err != nil:
+		return payload, apperror.BadRequest("invalid_patch", err.Error()).Wrap(err)
+	}
+
+	// compare the members before and after the patch
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(document, &before); err != nil {
+		return payload, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return payload, apperror.BadRequest("invalid_patch", "the patched transaction must be a json object").Wrap(err)
+	}
+
+	var fields []apperror.FieldError
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			fields = append(fields, apperror.FieldError{Field: name, Code: "not_patchable", Message: name + " cannot be patched"})
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok && !optionalPatchMembers[name] {
+			fields = append(fields, apperror.FieldError{Field: name, Code: "required", Message: name + " cannot be removed"})
+		}
+	}
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return payload, apperror.Validation(fields...)
+	}
+
+	if err := json.Unmarshal(patched, &payload); err != nil {
+		return payload, apperror.FromBinding(err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,206 @@
+package transactioncontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/service/transaction_service"
+	"go-findest-rest-api/util"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// maxRefundReason bounds the reason of a refund, in characters
+const maxRefundReason = 500
+
+var errNotRefundable = apperror.Conflict("not_refundable", "only success or partially refunded transactions can be refunded")
+
+// CreateRefund gives back part or all of a transaction, refunds never exceed its amount in total. The transaction
+// becomes partially_refunded, or refunded once nothing is left to refund
+func (tc *TransactionController) CreateRefund(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// bind payload into json
+	var payload dto.RefundCreate
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// validate reason
+	reason := strings.TrimSpace(payload.Reason)
+	if reasonErr := validateRefundReason(reason); reasonErr != nil {
+		util.Error(c, reasonErr)
+		return
+	}
+
+	// check if transaction exist
+	transaction, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errTransactionNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// check if the client refunds the version it read
+	if !util.IfMatch(c, util.ETag(transaction.Version.Version)) {
+		util.Error(c, errPreconditionFailed)
+		return
+	}
+
+	// check if the transaction may be refunded
+	if !model.IsRefundable(transaction.Status) {
+		util.Error(c, errNotRefundable)
+		return
+	}
+
+	// validate amount in the minor unit of the transaction currency, up to what is left to refund
+	amount, currency, amountErr := transactionservice.ParseAmount(payload.Amount, transaction.Currency)
+	if amountErr != nil {
+		util.Error(c, amountErr)
+		return
+	}
+	if refundable := transaction.Amount - transaction.RefundedAmount; amount > refundable {
+		util.Error(c, apperror.Validation(apperror.FieldError{
+			Field:   "amount",
+			Code:    "exceeds_refundable",
+			Message: fmt.Sprintf("amount can be at most %s %s", money.New(refundable, currency.Code), currency.Code),
+		}))
+		return
+	}
+
+	// save refund and the refunded transaction to database along with its refunded event and journal
+	refunded := *transaction
+	refunded.RefundedAmount = transaction.RefundedAmount + amount
+	refunded.Status = model.RefundedStatus(transaction.Amount, refunded.RefundedAmount)
+	refunded.UpdatedAt = time.Now()
+
+	var refund *model.Refund
+	var refundedTransaction *model.Transaction
+	saveErr := tc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		var err error
+		refundedTransaction, err = tc.TransactionRepo.Save(ctx, &refunded, id)
+		if err != nil {
+			return err
+		}
+
+		refund, err = tc.RefundRepo.Create(ctx, &model.Refund{
+			TransactionID: transaction.ID,
+			Amount:        amount,
+			Currency:      transaction.Currency,
+			Reason:        reason,
+			Actor:         util.Actor(c),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tc.recordEvent(c, ctx, model.EventRefunded, transaction, refundedTransaction); err != nil {
+			return err
+		}
+
+		return tc.Ledger.Post(ctx, ledger.Refund(*refund, transaction.UserID))
+	})
+	if saveErr != nil {
+		util.Error(c, saveConflict(c, saveErr))
+		return
+	}
+
+	// build response
+	c.Header("ETag", util.ETag(refundedTransaction.Version.Version))
+	res := dto.TransactionRefundResponse{
+		Refund:      buildRefundResponse(*refund),
+		Transaction: dto.NewTransactionResponse(*refundedTransaction),
+	}
+
+	// return response
+	util.Created(c, "transaction refunded successfully", res)
+}
+
+func (tc *TransactionController) GetTransactionRefunds(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if transaction exist
+	_, firstErr := tc.TransactionRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errTransactionNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// fetch refunds of the transaction, oldest first
+	refunds, findErr := tc.RefundRepo.Find(c.Request.Context(), repository.Filter{
+		Where: repository.Eq("transaction_id", id),
+		Sort:  []repository.Sort{{Field: "id"}},
+	})
+	if findErr != nil {
+		util.Error(c, findErr)
+		return
+	}
+
+	// build response
+	res := make([]dto.RefundResponse, 0, len(refunds))
+	for _, refund := range refunds {
+		res = append(res, buildRefundResponse(refund))
+	}
+
+	// return response
+	util.Success(c, "transaction refunds fetched successfully", res)
+}
+
+func validateRefundReason(reason string) error {
+	switch {
+	case reason == "":
+		return apperror.Validation(apperror.FieldError{
+			Field:   "reason",
+			Code:    "required",
+			Message: "reason is required",
+		})
+	case len([]rune(reason)) > maxRefundReason:
+		return apperror.Validation(apperror.FieldError{
+			Field:   "reason",
+			Code:    "too_long",
+			Message: fmt.Sprintf("reason can have at most %d characters", maxRefundReason),
+		})
+	}
+
+	return nil
+}
+
+func buildRefundResponse(refund model.Refund) dto.RefundResponse {
+	return dto.RefundResponse{
+		ID:            refund.ID,
+		TransactionID: refund.TransactionID,
+		Amount:        money.New(refund.Amount, refund.Currency),
+		Currency:      refund.Currency,
+		Reason:        refund.Reason,
+		Actor:         refund.Actor,
+		CreatedAt:     refund.CreatedAt,
+	}
+}
@@ -0,0 +1,357 @@
+package usercontroller
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/pagination"
+	"go-findest-rest-api/repository"
+	"go-findest-rest-api/util"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var (
+	errBlankName = apperror.Validation(apperror.FieldError{
+		Field:   "name",
+		Code:    "required",
+		Message: "name must not be blank",
+	})
+	errUserNotFound        = apperror.NotFound("user_not_found", "user not found or already deleted")
+	errUserHasTransactions = apperror.Conflict("user_has_transactions", "user still has transactions, delete them first")
+)
+
+type UserController struct {
+	UserRepo        repository.DatabaseRepository[model.User]
+	TransactionRepo repository.DatabaseRepository[model.Transaction]
+	Transactor      repository.Transactor
+	Ledger          ledger.Ledger
+}
+
+func NewUserController(
+	userRepo repository.DatabaseRepository[model.User],
+	transactionRepo repository.DatabaseRepository[model.Transaction],
+	transactor repository.Transactor,
+	ledger ledger.Ledger,
+) *UserController {
+	return &UserController{
+		UserRepo:        userRepo,
+		TransactionRepo: transactionRepo,
+		Transactor:      transactor,
+		Ledger:          ledger,
+	}
+}
+
+func (uc *UserController) CreateUser(c *gin.Context) {
+	// bind payload into json
+	var payload dto.UserCreate
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// validate name
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		util.Error(c, errBlankName)
+		return
+	}
+
+	// insert user into database
+	user, createErr := uc.UserRepo.Create(c.Request.Context(), &model.User{Name: name})
+	if createErr != nil {
+		util.Error(c, createErr)
+		return
+	}
+
+	// return response
+	util.Created(c, "user created successfully", buildUserResponse(*user))
+}
+
+func (uc *UserController) GetUsers(c *gin.Context) {
+	// bind payload into json
+	var payload dto.GetUsersQuery
+	if err := c.ShouldBindQuery(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// map payload into filters
+	var conditions []repository.Expression
+	if q := strings.TrimSpace(payload.Query); q != "" {
+		conditions = append(conditions, repository.Contains("name", q))
+	}
+	where := repository.All(conditions...)
+
+	// resolve requested page
+	page, pageErr := pagination.NewPage(payload.Page, payload.PageSize, payload.After, payload.Before)
+	if pageErr != nil {
+		util.Error(c, pageErr)
+		return
+	}
+
+	// count all matching users
+	total, countErr := uc.UserRepo.Count(c.Request.Context(), repository.Filter{Where: where})
+	if countErr != nil {
+		util.Error(c, countErr)
+		return
+	}
+
+	// find users of the page
+	users, findErr := uc.UserRepo.Find(c.Request.Context(), page.Filter(where))
+	if findErr != nil {
+		util.Error(c, findErr)
+		return
+	}
+	users, nextCursor, prevCursor := pagination.Rows(page, users, userCursor)
+
+	// build response
+	res := dto.Pagination[dto.UserResponse]{
+		TotalRecords: int(total),
+		Page:         page.Number,
+		PageSize:     page.Size,
+		NextCursor:   nextCursor,
+		PrevCursor:   prevCursor,
+		Data:         make([]dto.UserResponse, 0, len(users)),
+	}
+	for _, user := range users {
+		res.Data = append(res.Data, buildUserResponse(user))
+	}
+
+	// return response
+	util.Success(c, "user(s) fetched successfully", res)
+}
+
+func (uc *UserController) GetUserById(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if user exist
+	user, firstErr := uc.UserRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errUserNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// return response
+	util.Success(c, "user fetched successfully", buildUserResponse(*user))
+}
+
+func (uc *UserController) GetUserSummary(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if user exist
+	_, firstErr := uc.UserRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errUserNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// aggregate transactions of the user per currency, overall and per status
+	filter := repository.Filter{Where: repository.Eq("user_id", id)}
+	totals, err1 := uc.TransactionRepo.Stats(c.Request.Context(), filter, repository.Aggregate{
+		Amount:  model.TransactionNetAmount,
+		Time:    "created_at",
+		GroupBy: []string{"currency"},
+	})
+	byStatus, err2 := uc.TransactionRepo.Stats(c.Request.Context(), filter, repository.Aggregate{
+		Amount:  model.TransactionNetAmount,
+		Time:    "created_at",
+		GroupBy: []string{"status", "currency"},
+	})
+
+	// handling error
+	if err := errors.Join(err1, err2); err != nil {
+		util.Error(c, err)
+		return
+	}
+
+	// build response
+	res := dto.UserSummaryResponse{
+		UserID:   id,
+		Totals:   make([]dto.TransactionStatsAttr, 0, len(totals)),
+		ByStatus: make([]dto.TransactionStatsAttr, 0, len(byStatus)),
+	}
+	res.Totals = append(res.Totals, totals...)
+	res.ByStatus = append(res.ByStatus, byStatus...)
+
+	// return response
+	util.Success(c, "user summary fetched successfully", res)
+}
+
+// GetUserBalance returns what the user holds per currency according to the ledger, payments of successful
+// transactions minus their refunds
+func (uc *UserController) GetUserBalance(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// check if user exist
+	_, firstErr := uc.UserRepo.First(c.Request.Context(), id)
+	if firstErr != nil {
+		if errors.Is(firstErr, gorm.ErrRecordNotFound) {
+			util.Error(c, errUserNotFound)
+			return
+		}
+
+		util.Error(c, firstErr)
+		return
+	}
+
+	// fetch balances of the user accounts
+	balances, balanceErr := uc.Ledger.Balances(c.Request.Context(), id)
+	if balanceErr != nil {
+		util.Error(c, balanceErr)
+		return
+	}
+
+	// build response
+	res := dto.UserBalanceResponse{
+		UserID:   id,
+		Balances: make([]dto.UserBalanceAttr, 0, len(balances)),
+	}
+	for _, balance := range balances {
+		res.Balances = append(res.Balances, dto.UserBalanceAttr{
+			Currency: balance.Currency,
+			Balance:  money.New(balance.Balance, balance.Currency),
+		})
+	}
+
+	// return response
+	util.Success(c, "user balance fetched successfully", res)
+}
+
+func (uc *UserController) UpdateUser(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// bind payload into json
+	var payload dto.UserUpdate
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		util.Error(c, apperror.FromBinding(err))
+		return
+	}
+
+	// validate name
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		util.Error(c, errBlankName)
+		return
+	}
+
+	// lock the user so a delete running meanwhile is not undone by saving the user as it was read, deleting a
+	// user locks it too
+	var updatedUser *model.User
+	updateErr := uc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		user, err := uc.UserRepo.First(repository.ForUpdate(ctx), id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+
+			return err
+		}
+
+		// update user and save it to database
+		user.Name = name
+		updatedUser, err = uc.UserRepo.Save(ctx, user, id)
+
+		return err
+	})
+	if updateErr != nil {
+		util.Error(c, updateErr)
+		return
+	}
+
+	// return response
+	util.Success(c, "user updated successfully", buildUserResponse(*updatedUser))
+}
+
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	// get param from context
+	id, idErr := util.ParamID(c, "id")
+	if idErr != nil {
+		util.Error(c, idErr)
+		return
+	}
+
+	// lock the user so no transaction is created for it between counting its transactions and deleting it,
+	// creating a transaction locks the user too
+	deleteErr := uc.Transactor.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		user, err := uc.UserRepo.First(repository.ForUpdate(ctx), id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+
+			return err
+		}
+
+		// refuse to delete a user whose transactions would be left without an owner
+		transactionCount, err := uc.TransactionRepo.Count(ctx, repository.Filter{Where: repository.Eq("user_id", id)})
+		if err != nil {
+			return err
+		}
+		if transactionCount > 0 {
+			return errUserHasTransactions
+		}
+
+		// deactivate user and save it to database
+		user.SoftDelete = model.Deleted(time.Now())
+		_, err = uc.UserRepo.Save(ctx, user, id)
+
+		return err
+	})
+	if deleteErr != nil {
+		util.Error(c, deleteErr)
+		return
+	}
+
+	// return response
+	util.Success(c, "user deleted successfully", nil)
+}
+
+func buildUserResponse(user model.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+func userCursor(u model.User) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+}
@@ -0,0 +1,572 @@
+package usercontroller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/mock"
+	"go-findest-rest-api/controller/user_controller"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
+	mocks "go-findest-rest-api/mock"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/pagination"
+	"go-findest-rest-api/repository"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setUpRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	return r
+}
+
+func TestCreateUser(t *testing.T) {
+	testCases := map[string]struct {
+		mockBody       any
+		mockCreateErr  []any
+		expectedStatus int
+	}{
+		"successfully created user": {
+			mockBody:       &dto.UserCreate{Name: "Daiki Tsuneta"},
+			mockCreateErr:  []any{&model.User{ID: 1, Name: "Daiki Tsuneta"}, nil},
+			expectedStatus: http.StatusCreated,
+		},
+		"error cannot bind payload into json": {
+			mockBody:       "wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error name is required": {
+			mockBody:       &dto.UserCreate{},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error name is blank": {
+			mockBody:       &dto.UserCreate{Name: "   "},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error cannot insert user into database": {
+			mockBody:       &dto.UserCreate{Name: "Daiki Tsuneta"},
+			mockCreateErr:  []any{(*model.User)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				new(mocks.MockLedger),
+			)
+
+			mockUserRepo.On("Create", mock.Anything, mock.Anything).Return(test.mockCreateErr...).Once()
+
+			router := setUpRouter()
+			router.POST("/api/users", controller.CreateUser)
+
+			w := httptest.NewRecorder()
+
+			body, _ := json.Marshal(test.mockBody)
+			req, _ := http.NewRequest(http.MethodPost, "/api/users", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		expectedWhere  repository.Expression
+		expectedFilter repository.Filter
+		mockCountErr   []any
+		mockFindErr    []any
+		expectedStatus int
+	}{
+		"successfully get users with search": {
+			testURL:       "/api/users?q=%25tsu&pageSize=5",
+			expectedWhere: repository.All(repository.Contains("name", "%tsu")),
+			expectedFilter: repository.Filter{
+				Where: repository.All(repository.Contains("name", "%tsu")),
+				Sort:  pagination.Newest(),
+				Limit: 6,
+			},
+			mockCountErr: []any{int64(1), nil},
+			mockFindErr: []any{[]model.User{
+				{ID: 1, Name: "Daiki Tsuneta", CreatedAt: time.Now()},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get users without search": {
+			testURL:       "/api/users",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(),
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{[]model.User{}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid cursor": {
+			testURL:        "/api/users?before=wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error count users": {
+			testURL:        "/api/users",
+			expectedWhere:  repository.All(),
+			mockCountErr:   []any{int64(0), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error get users": {
+			testURL:       "/api/users",
+			expectedWhere: repository.All(),
+			expectedFilter: repository.Filter{
+				Where: repository.All(),
+				Sort:  pagination.Newest(),
+				Limit: pagination.DefaultPageSize + 1,
+			},
+			mockCountErr:   []any{int64(0), nil},
+			mockFindErr:    []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				new(mocks.MockLedger),
+			)
+
+			mockUserRepo.On("Count", mock.Anything, repository.Filter{Where: test.expectedWhere}).Return(test.mockCountErr...).Once()
+			mockUserRepo.On("Find", mock.Anything, test.expectedFilter).Return(test.mockFindErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/users", controller.GetUsers)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetUserById(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		mockFirstErr   []any
+		expectedStatus int
+	}{
+		"successfully get user by id": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/users/wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user not found": {
+			testURL:        "/api/users/10",
+			mockFirstErr:   []any{nil, gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error internal server error": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{nil, errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				new(mocks.MockLedger),
+			)
+
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/users/:id", controller.GetUserById)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+// transactionKey marks the context of the work run by markingTransactor
+type transactionKey struct{}
+
+// markingTransactor runs the work right away like MockTransactor, with a context telling it ran in a transaction
+type markingTransactor struct{}
+
+func (markingTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, transactionKey{}, true))
+}
+
+func inTransaction(ctx context.Context) bool {
+	return ctx.Value(transactionKey{}) != nil
+}
+
+func TestUpdateUser(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		mockBody       any
+		mockFirstErr   []any
+		mockSaveErr    []any
+		expectedStatus int
+	}{
+		"successfully updated user": {
+			testURL:        "/api/users/1",
+			mockBody:       &dto.UserUpdate{Name: "Satoru Iguchi"},
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockSaveErr:    []any{&model.User{ID: 1, Name: "Satoru Iguchi"}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/users/wrong-format",
+			mockBody:       &dto.UserUpdate{Name: "Satoru Iguchi"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error cannot bind payload into json": {
+			testURL:        "/api/users/1",
+			mockBody:       "wrong-format",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error name is blank": {
+			testURL:        "/api/users/1",
+			mockBody:       &dto.UserUpdate{Name: " "},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"error user not found": {
+			testURL:        "/api/users/1",
+			mockBody:       &dto.UserUpdate{Name: "Satoru Iguchi"},
+			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error cannot update user into database": {
+			testURL:        "/api/users/1",
+			mockBody:       &dto.UserUpdate{Name: "Satoru Iguchi"},
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockSaveErr:    []any{(*model.User)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				markingTransactor{},
+				new(mocks.MockLedger),
+			)
+
+			// the user is read and saved in the same database transaction, so a delete cannot happen in between
+			mockUserRepo.On("First", mock.MatchedBy(inTransaction), mock.Anything).Return(test.mockFirstErr...).Once()
+			mockUserRepo.On("Save", mock.MatchedBy(inTransaction), mock.Anything).Return(test.mockSaveErr...)
+
+			router := setUpRouter()
+			router.PUT("/api/users/:id", controller.UpdateUser)
+
+			w := httptest.NewRecorder()
+
+			body, _ := json.Marshal(test.mockBody)
+			req, _ := http.NewRequest(http.MethodPut, test.testURL, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	testCases := map[string]struct {
+		testURL        string
+		mockFirstErr   []any
+		mockCountErr   []any
+		mockSaveErr    []any
+		expectedStatus int
+	}{
+		"successfully deleted user": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockCountErr:   []any{int64(0), nil},
+			mockSaveErr:    []any{&model.User{ID: 1, SoftDelete: model.SoftDelete{IsDeleted: true}}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"error user not found": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error user still has transactions": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockCountErr:   []any{int64(3), nil},
+			expectedStatus: http.StatusConflict,
+		},
+		"error count transactions": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockCountErr:   []any{int64(0), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"error cannot delete user into database": {
+			testURL:        "/api/users/1",
+			mockFirstErr:   []any{&model.User{ID: 1}, nil},
+			mockCountErr:   []any{int64(0), nil},
+			mockSaveErr:    []any{(*model.User)(nil), errors.New("")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				new(mocks.MockLedger),
+			)
+
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Count", mock.Anything, repository.Filter{Where: repository.Eq("user_id", uint(1))}).Return(test.mockCountErr...).Once()
+			mockUserRepo.On("Save", mock.Anything, mock.Anything).Return(test.mockSaveErr...)
+
+			router := setUpRouter()
+			router.DELETE("/api/users/:id", controller.DeleteUser)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodDelete, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetUserSummary(t *testing.T) {
+	firstAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastAt := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		testURL         string
+		mockFirstErr    []any
+		mockOverallErr  []any
+		mockByStatusErr []any
+		expectedStatus  int
+	}{
+		"successfully get user summary": {
+			testURL:      "/api/users/1/summary",
+			mockFirstErr: []any{&model.User{ID: 1}, nil},
+			mockOverallErr: []any{[]dto.TransactionStatsAttr{
+				{Currency: "IDR", Count: 3, Sum: 600, Average: 200, Min: 100, Max: 300, FirstTransactionAt: &firstAt, LastTransactionAt: &lastAt},
+				{Currency: "JPY", Count: 1, Sum: 500, Average: 500, Min: 500, Max: 500, FirstTransactionAt: &firstAt, LastTransactionAt: &firstAt},
+			}, nil},
+			mockByStatusErr: []any{[]dto.TransactionStatsAttr{
+				{Status: "success", Currency: "IDR", Count: 2, Sum: 500, Average: 250, Min: 200, Max: 300, FirstTransactionAt: &firstAt, LastTransactionAt: &lastAt},
+				{Status: "failed", Currency: "IDR", Count: 1, Sum: 100, Average: 100, Min: 100, Max: 100, FirstTransactionAt: &firstAt, LastTransactionAt: &firstAt},
+				{Status: "success", Currency: "JPY", Count: 1, Sum: 500, Average: 500, Min: 500, Max: 500, FirstTransactionAt: &firstAt, LastTransactionAt: &firstAt},
+			}, nil},
+			expectedStatus: http.StatusOK,
+		},
+		"successfully get summary of user without transactions": {
+			testURL:         "/api/users/1/summary",
+			mockFirstErr:    []any{&model.User{ID: 1}, nil},
+			mockOverallErr:  []any{[]dto.TransactionStatsAttr{}, nil},
+			mockByStatusErr: []any{[]dto.TransactionStatsAttr{}, nil},
+			expectedStatus:  http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/users/wrong-format/summary",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user not found": {
+			testURL:        "/api/users/1/summary",
+			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error internal server error": {
+			testURL:         "/api/users/1/summary",
+			mockFirstErr:    []any{&model.User{ID: 1}, nil},
+			mockOverallErr:  []any{nil, errors.New("")},
+			mockByStatusErr: []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				new(mocks.MockLedger),
+			)
+
+			filter := repository.Filter{Where: repository.Eq("user_id", uint(1))}
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockTransactionRepo.On("Stats", mock.Anything, filter, repository.Aggregate{
+				Amount:  model.TransactionNetAmount,
+				Time:    "created_at",
+				GroupBy: []string{"currency"},
+			}).Return(test.mockOverallErr...).Once()
+			mockTransactionRepo.On("Stats", mock.Anything, filter, repository.Aggregate{
+				Amount:  model.TransactionNetAmount,
+				Time:    "created_at",
+				GroupBy: []string{"status", "currency"},
+			}).Return(test.mockByStatusErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/users/:id/summary", controller.GetUserSummary)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetUserBalance(t *testing.T) {
+	testCases := map[string]struct {
+		testURL          string
+		mockFirstErr     []any
+		mockBalancesErr  []any
+		expectedBalances string
+		expectedStatus   int
+	}{
+		"successfully get user balance": {
+			testURL:      "/api/users/1/balance",
+			mockFirstErr: []any{&model.User{ID: 1}, nil},
+			mockBalancesErr: []any{[]ledger.Balance{
+				{Currency: "IDR", Balance: 150000},
+				{Currency: "USD", Balance: 1250},
+			}, nil},
+			expectedBalances: `[{"currency":"IDR","balance":"1500.00"},{"currency":"USD","balance":"12.50"}]`,
+			expectedStatus:   http.StatusOK,
+		},
+		"successfully get balance of user without postings": {
+			testURL:          "/api/users/1/balance",
+			mockFirstErr:     []any{&model.User{ID: 1}, nil},
+			mockBalancesErr:  []any{[]ledger.Balance{}, nil},
+			expectedBalances: `[]`,
+			expectedStatus:   http.StatusOK,
+		},
+		"error invalid id": {
+			testURL:        "/api/users/wrong-format/balance",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"error user not found": {
+			testURL:        "/api/users/1/balance",
+			mockFirstErr:   []any{(*model.User)(nil), gorm.ErrRecordNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"error internal server error": {
+			testURL:         "/api/users/1/balance",
+			mockFirstErr:    []any{&model.User{ID: 1}, nil},
+			mockBalancesErr: []any{nil, errors.New("")},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(mocks.MockDatabaseRepository[model.User])
+			mockTransactionRepo := new(mocks.MockDatabaseRepository[model.Transaction])
+			mockLedger := new(mocks.MockLedger)
+
+			controller := usercontroller.NewUserController(
+				mockUserRepo,
+				mockTransactionRepo,
+				new(mocks.MockTransactor),
+				mockLedger,
+			)
+
+			mockUserRepo.On("First", mock.Anything, mock.Anything).Return(test.mockFirstErr...).Once()
+			mockLedger.On("Balances", mock.Anything, uint(1)).Return(test.mockBalancesErr...).Once()
+
+			router := setUpRouter()
+			router.GET("/api/users/:id/balance", controller.GetUserBalance)
+
+			w := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodGet, test.testURL, nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusOK {
+				var res struct {
+					Data struct {
+						UserID   uint            `json:"userId"`
+						Balances json.RawMessage `json:"balances"`
+					} `json:"data"`
+				}
+				_ = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.Equal(t, uint(1), res.Data.UserID)
+				assert.Equal(t, test.expectedBalances, string(res.Data.Balances))
+			}
+		})
+	}
+}
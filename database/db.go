@@ -2,6 +2,8 @@ package database
 
 import (
 	"fmt"
+	"go-findest-rest-api/idempotency"
+	"go-findest-rest-api/ledger"
 	"go-findest-rest-api/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -16,8 +18,10 @@ func InitDb(host string, dbUser string, dbPassword string, dbName string, dbPort
 		panic(err)
 	}
 
-	db.AutoMigrate(&model.Transaction{})
-	db.AutoMigrate(&model.User{})
-
 	Database = db
 }
+
+// AutoMigrate syncs the schema with the models, it is meant for development only, deployments run migrate up
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&model.User{}, &model.Transaction{}, &model.TransactionEvent{}, &model.Refund{}, &ledger.Account{}, &ledger.JournalEntry{}, &ledger.Posting{}, &idempotency.Record{})
+}
@@ -1,6 +1,10 @@
 package dto
 
 type Pagination[T any] struct {
-	TotalRecords int `json:"totalRecords"`
-	Data         []T `json:"data"`
+	TotalRecords int    `json:"totalRecords"`
+	Page         int    `json:"page,omitempty"`
+	PageSize     int    `json:"pageSize"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+	PrevCursor   string `json:"prevCursor,omitempty"`
+	Data         []T    `json:"data"`
 }
@@ -1,17 +1,63 @@
 package dto
 
+import (
+	"go-findest-rest-api/money"
+	"time"
+)
+
+type GetDashboardQuery struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Timezone string `form:"tz"`
+}
+
+type GetTimeseriesQuery struct {
+	GetDashboardQuery
+	Interval string `form:"interval"`
+}
+
 type DashboardResponse struct {
+	Range                      DashboardRange                           `json:"range"`
 	SuccessfulTransactionToday DashboardPagination[TransactionResponse] `json:"successfulTransactionToday"`
 	AverageTransactionPerUser  []AverageTransactionAttr                 `json:"averageTransactionPerUser"`
 	LatestTransaction          DashboardPagination[TransactionResponse] `json:"latestTransaction"`
 }
 
+// DashboardRange is the window every section is scoped to, To is exclusive
+type DashboardRange struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Timezone string    `json:"timezone"`
+}
+
 type AverageTransactionAttr struct {
-	UserId         uint    `json:"userId"`
-	AvgTransaction float64 `json:"avgTransaction"`
+	UserId         uint        `json:"userId"`
+	Currency       string      `json:"currency"`
+	AvgTransaction money.Money `json:"avgTransaction"`
 }
 
 type DashboardPagination[T any] struct {
 	TotalRecords int `json:"totalRecords"`
 	Transactions []T `json:"transactions"`
 }
+
+type TimeseriesResponse struct {
+	Range    DashboardRange     `json:"range"`
+	Interval string             `json:"interval"`
+	Buckets  []TimeseriesBucket `json:"buckets"`
+}
+
+// TimeseriesBucket totals the transactions created in [Start, next bucket), every status and currency
+// seen in the range is listed in each bucket even when it has no transactions there. Sum is keyed by currency
+type TimeseriesBucket struct {
+	Start    time.Time              `json:"start"`
+	Count    int64                  `json:"count"`
+	Sum      map[string]money.Money `json:"sum"`
+	ByStatus []TimeseriesStatus     `json:"byStatus"`
+}
+
+type TimeseriesStatus struct {
+	Status string                 `json:"status"`
+	Count  int64                  `json:"count"`
+	Sum    map[string]money.Money `json:"sum"`
+}
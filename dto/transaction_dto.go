@@ -1,27 +1,256 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"time"
+)
 
+// TransactionCreate takes the amount as a decimal number or string in the major unit of Currency. Metadata is
+// kept raw, so its numbers are read without losing precision
 type TransactionCreate struct {
-	UserID uint    `json:"userId"`
-	Amount float64 `json:"amount"`
-	Status string  `json:"status"`
+	UserID      uint            `json:"userId"`
+	Amount      json.Number     `json:"amount"`
+	Currency    string          `json:"currency"`
+	Status      string          `json:"status"`
+	Description string          `json:"description"`
+	Metadata    json.RawMessage `json:"metadata"`
+	Tags        []string        `json:"tags"`
 }
 
+// TransactionBulkResult is the outcome of one item of a bulk create, Index is its position in the request
+type TransactionBulkResult struct {
+	Index       int                  `json:"index"`
+	Result      string               `json:"result"`
+	Transaction *TransactionResponse `json:"transaction,omitempty"`
+	Error       *BulkItemError       `json:"error,omitempty"`
+}
+
+type BulkItemError struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Fields  []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// TransactionImportRowError is a row of an import that was not imported, Line is where it starts in the csv
+type TransactionImportRowError struct {
+	Line int `json:"line"`
+	BulkItemError
+}
+
+// TransactionImportResponse counts the rows of an import, Valid rows are imported unless DryRun is set
+type TransactionImportResponse struct {
+	DryRun   bool                        `json:"dryRun"`
+	Rows     int                         `json:"rows"`
+	Valid    int                         `json:"valid"`
+	Imported int                         `json:"imported"`
+	Failed   int                         `json:"failed"`
+	Errors   []TransactionImportRowError `json:"errors"`
+}
+
+// TransactionBulkSelection names the transactions of a bulk change, either by IDs or by a listing filter
+type TransactionBulkSelection struct {
+	IDs    []uint                `json:"ids"`
+	Filter *GetTransactionsQuery `json:"filter"`
+}
+
+type TransactionBulkStatus struct {
+	TransactionBulkSelection
+	Status string `json:"status"`
+}
+
+// TransactionBulkChangeResponse counts the selected transactions, Skipped ones were found but left unchanged
+type TransactionBulkChangeResponse struct {
+	Affected    int    `json:"affected"`
+	Skipped     int    `json:"skipped"`
+	NotFound    int    `json:"notFound"`
+	SkippedIDs  []uint `json:"skippedIds"`
+	NotFoundIDs []uint `json:"notFoundIds"`
+}
+
+type TransactionBulkResponse struct {
+	Mode    string                  `json:"mode"`
+	Created int                     `json:"created"`
+	Failed  int                     `json:"failed"`
+	Results []TransactionBulkResult `json:"results"`
+}
+
+// GetTransactionsQuery filters listings, bulk changes take the same filter as json without paging and sorting.
+// Metadata is read from metadata.<key> query parameters, which cannot be bound
 type GetTransactionsQuery struct {
-	UserID uint   `form:"userId"`
-	Status string `form:"status"`
+	UserIDs     []string          `form:"userId" json:"userId"`
+	Statuses    []string          `form:"status" json:"status"`
+	Currencies  []string          `form:"currency" json:"currency"`
+	Match       string            `form:"match" json:"match"`
+	Sort        string            `form:"sort" json:"-"`
+	MinAmount   string            `form:"minAmount" json:"minAmount"`
+	MaxAmount   string            `form:"maxAmount" json:"maxAmount"`
+	CreatedFrom string            `form:"createdFrom" json:"createdFrom"`
+	CreatedTo   string            `form:"createdTo" json:"createdTo"`
+	UpdatedFrom string            `form:"updatedFrom" json:"updatedFrom"`
+	UpdatedTo   string            `form:"updatedTo" json:"updatedTo"`
+	Tags        []string          `form:"tag" json:"tag"`
+	Metadata    map[string]string `form:"-" json:"metadata"`
+	Page        int               `form:"page" json:"-"`
+	PageSize    int               `form:"pageSize" json:"-"`
+	After       string            `form:"after" json:"-"`
+	Before      string            `form:"before" json:"-"`
+}
+
+// ExportTransactionsQuery takes the listing filters, Columns is a comma separated list and Timezone formats
+// the timestamps
+type ExportTransactionsQuery struct {
+	GetTransactionsQuery
+	Format   string `form:"format"`
+	Columns  string `form:"columns"`
+	Timezone string `form:"tz"`
 }
 
 type TransactionResponse struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"userId"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID             uint           `json:"id"`
+	UserID         uint           `json:"userId"`
+	Amount         money.Money    `json:"amount"`
+	Currency       string         `json:"currency"`
+	RefundedAmount money.Money    `json:"refundedAmount"`
+	Status         string         `json:"status"`
+	Description    string         `json:"description"`
+	Metadata       map[string]any `json:"metadata"`
+	Tags           []string       `json:"tags"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	Version        uint           `json:"version"`
+	DeletedAt      *time.Time     `json:"deletedAt,omitempty"`
+}
+
+// NewTransactionResponse builds the representation of transaction every endpoint returns
+func NewTransactionResponse(transaction model.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:             transaction.ID,
+		UserID:         transaction.UserID,
+		Amount:         money.New(transaction.Amount, transaction.Currency),
+		Currency:       transaction.Currency,
+		RefundedAmount: money.New(transaction.RefundedAmount, transaction.Currency),
+		Status:         transaction.Status,
+		Description:    transaction.Description,
+		Metadata:       transaction.Metadata,
+		Tags:           transaction.Tags,
+		CreatedAt:      transaction.CreatedAt,
+		UpdatedAt:      transaction.UpdatedAt,
+		Version:        transaction.Version.Version,
+		DeletedAt:      transaction.DeletedAt,
+	}
 }
 
 type TransactionUpdate struct {
 	Status string `json:"status"`
 }
+
+// TransactionPatch is the document a PATCH request changes, its members are the only fields of a transaction
+// that can be patched. Amount is a decimal in the major unit of Currency, as on create
+type TransactionPatch struct {
+	Amount      json.Number     `json:"amount"`
+	Currency    string          `json:"currency"`
+	Status      string          `json:"status"`
+	Description string          `json:"description"`
+	Metadata    json.RawMessage `json:"metadata"`
+	Tags        []string        `json:"tags"`
+}
+
+// TransactionPurgeResponse reports a purge of the transactions deleted before DeletedBefore
+type TransactionPurgeResponse struct {
+	Purged        int64     `json:"purged"`
+	DeletedBefore time.Time `json:"deletedBefore"`
+}
+
+// TransactionStatusResponse describes one status of the state machine, Final statuses have no transitions and
+// cannot be refunded
+type TransactionStatusResponse struct {
+	Status      string   `json:"status"`
+	Initial     bool     `json:"initial"`
+	Final       bool     `json:"final"`
+	Transitions []string `json:"transitions"`
+	Refundable  bool     `json:"refundable"`
+}
+
+type TransactionTransitionsResponse struct {
+	ID          uint     `json:"id"`
+	Status      string   `json:"status"`
+	Transitions []string `json:"transitions"`
+	Refundable  bool     `json:"refundable"`
+}
+
+// RefundCreate gives back Amount of a transaction, a decimal in the major unit of its currency
+type RefundCreate struct {
+	Amount json.Number `json:"amount"`
+	Reason string      `json:"reason"`
+}
+
+type RefundResponse struct {
+	ID            uint        `json:"id"`
+	TransactionID uint        `json:"transactionId"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	Reason        string      `json:"reason"`
+	Actor         string      `json:"actor"`
+	CreatedAt     time.Time   `json:"createdAt"`
+}
+
+// TransactionRefundResponse is a created refund along with the transaction it changed
+type TransactionRefundResponse struct {
+	Refund      RefundResponse      `json:"refund"`
+	Transaction TransactionResponse `json:"transaction"`
+}
+
+// TransactionEventResponse is one entry of the history of a transaction, OldValues is null for created events
+type TransactionEventResponse struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	OldValues json.RawMessage `json:"oldValues"`
+	NewValues json.RawMessage `json:"newValues"`
+	Actor     string          `json:"actor"`
+	RequestID string          `json:"requestId"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// TransactionStatsAttr holds amounts in minor units of Currency, they are only comparable within one currency
+type TransactionStatsAttr struct {
+	UserID             uint       `json:"userId,omitempty"`
+	Status             string     `json:"status,omitempty"`
+	Currency           string     `json:"currency"`
+	Count              int64      `json:"count"`
+	Sum                int64      `json:"sum"`
+	Average            int64      `json:"average"`
+	Min                int64      `json:"min"`
+	Max                int64      `json:"max"`
+	FirstTransactionAt *time.Time `json:"firstTransactionAt"`
+	LastTransactionAt  *time.Time `json:"lastTransactionAt"`
+}
+
+// MarshalJSON writes the amounts as decimals of their currency, the same way TransactionResponse does
+func (s TransactionStatsAttr) MarshalJSON() ([]byte, error) {
+	type attr TransactionStatsAttr
+	return json.Marshal(struct {
+		attr
+		Sum     money.Money `json:"sum"`
+		Average money.Money `json:"average"`
+		Min     money.Money `json:"min"`
+		Max     money.Money `json:"max"`
+	}{
+		attr:    attr(s),
+		Sum:     money.New(s.Sum, s.Currency),
+		Average: money.New(s.Average, s.Currency),
+		Min:     money.New(s.Min, s.Currency),
+		Max:     money.New(s.Max, s.Currency),
+	})
+}
+
+// TransactionSeriesAttr is one bucket of a time series, Status and Currency are only set when grouping by them
+type TransactionSeriesAttr struct {
+	Bucket   time.Time `json:"bucket"`
+	Status   string    `json:"status,omitempty"`
+	Currency string    `json:"currency,omitempty"`
+	Count    int64     `json:"count"`
+	Sum      int64     `json:"sum"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"go-findest-rest-api/money"
+	"time"
+)
+
+type UserCreate struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type GetUsersQuery struct {
+	Query    string `form:"q"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"pageSize"`
+	After    string `form:"after"`
+	Before   string `form:"before"`
+}
+
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type UserUpdate struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// UserSummaryResponse has one total per currency, amounts in different currencies are never added up
+type UserSummaryResponse struct {
+	UserID   uint                   `json:"userId"`
+	Totals   []TransactionStatsAttr `json:"totals"`
+	ByStatus []TransactionStatsAttr `json:"byStatus"`
+}
+
+// UserBalanceResponse has one balance per currency the user has ledger postings in
+type UserBalanceResponse struct {
+	UserID   uint              `json:"userId"`
+	Balances []UserBalanceAttr `json:"balances"`
+}
+
+type UserBalanceAttr struct {
+	Currency string      `json:"currency"`
+	Balance  money.Money `json:"balance"`
+}
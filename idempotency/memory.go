@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// MemoryStore keeps records in memory, it is meant for tests and single instance setups
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]Record{},
+	}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, record Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		return &existing, nil
+	}
+	s.records[record.Key] = record
+
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, status int, header http.Header, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	record.Status = status
+	record.Header = Header(header.Clone())
+	record.Body = append([]byte{}, body...)
+	s.records[key] = record
+
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
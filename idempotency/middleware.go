@@ -0,0 +1,161 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/util"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+var (
+	errInvalidKey = apperror.BadRequest("invalid_idempotency_key", "Idempotency-Key must be between 1 and 255 characters")
+	errKeyReused  = apperror.New(http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used with a different payload")
+	errInProgress = apperror.Conflict("idempotency_key_in_progress", "a request with this Idempotency-Key is still being processed")
+)
+
+// Middleware replays the stored response of requests repeating an Idempotency-Key on the same path within ttl.
+// Responses with a server error or to cancelled requests are not stored, so the request can be retried. Requests without the header pass through
+func Middleware(store Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(KeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			util.Error(c, errInvalidKey)
+			c.Abort()
+			return
+		}
+
+		// read the payload and put it back for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			util.Error(c, apperror.BadRequest(apperror.CodeInvalidRequest, "request body could not be read").Wrap(err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// keys are only unique per client and resource, the path holds its ids such as /transactions/1/refunds
+		now := time.Now()
+		record := Record{
+			Key:         util.Actor(c) + " " + c.Request.Method + " " + c.Request.URL.Path + " " + key,
+			Fingerprint: fingerprint(body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		existing, err := store.Reserve(c.Request.Context(), record)
+		if err != nil {
+			util.Error(c, err)
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				util.Error(c, errKeyReused)
+			case !existing.completed():
+				util.Error(c, errInProgress)
+			default:
+				for name, values := range existing.Header {
+					c.Writer.Header()[name] = values
+				}
+				c.Header(ReplayedHeader, "true")
+				c.Data(existing.Status, http.Header(existing.Header).Get("Content-Type"), existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		// the request context may be cancelled already, the outcome must be kept regardless. The reservation
+		// is released when the handler fails or panics, so the request can be retried
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(ctx, record.Key); err != nil {
+				log.Printf("releasing idempotency key: %v", err)
+			}
+		}()
+
+		// headers set before the handler ran, such as X-Request-ID, belong to this request only
+		before := c.Writer.Header().Clone()
+		writer := &recorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if !storable(writer.Status()) {
+			return
+		}
+		if err := store.Complete(ctx, record.Key, writer.Status(), responseHeader(before, writer.Header()), writer.body.Bytes()); err != nil {
+			log.Printf("storing idempotent response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// storable reports whether a response of status is the outcome of the request. Server errors and requests the
+// client went away from are not, retrying them must run the handler again
+func storable(status int) bool {
+	return status < http.StatusInternalServerError && status != apperror.StatusClientClosedRequest
+}
+
+// fingerprint hashes the payload, JSON is compacted first so formatting alone does not change it
+func fingerprint(body []byte) string {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, body); err == nil {
+		body = compacted.Bytes()
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseHeader returns the headers of after that the handler added or changed since before
+func responseHeader(before http.Header, after http.Header) http.Header {
+	header := http.Header{}
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			header[name] = slices.Clone(values)
+		}
+	}
+
+	return header
+}
+
+// recorder keeps a copy of the response body while writing it
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
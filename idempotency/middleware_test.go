@@ -0,0 +1,257 @@
+package idempotency_test
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/idempotency"
+	"go-findest-rest-api/middleware"
+	"go-findest-rest-api/util"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const payload = `{"userId":1,"amount":"1"}`
+
+// handlerStep is what the handler does on one of its calls
+type handlerStep func(c *gin.Context, router *gin.Engine)
+
+func created(c *gin.Context, _ *gin.Engine) {
+	c.Header("ETag", `"1"`)
+	c.Header("Location", "/api/transactions/1")
+	c.JSON(http.StatusCreated, gin.H{"id": 1})
+}
+
+func failed(c *gin.Context, _ *gin.Engine) {
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+}
+
+func panicked(c *gin.Context, _ *gin.Engine) {
+	panic("handler failed")
+}
+
+func cancelled(c *gin.Context, _ *gin.Engine) {
+	util.Error(c, context.Canceled)
+}
+
+// refunded answers with the id of the transaction in the path
+func refunded(c *gin.Context, _ *gin.Engine) {
+	c.JSON(http.StatusCreated, gin.H{"transactionId": c.Param("id")})
+}
+
+func newRouter(store idempotency.Store, ttl time.Duration, steps []handlerStep) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestID())
+
+	calls := 0
+	handler := func(c *gin.Context) {
+		step := steps[calls]
+		calls++
+		step(c, router)
+	}
+	router.POST("/api/transactions", idempotency.Middleware(store, ttl), handler)
+	router.POST("/api/transactions/:id/refunds", idempotency.Middleware(store, ttl), handler)
+
+	return router, &calls
+}
+
+func send(router *gin.Engine, key string, body string, requestID string) *httptest.ResponseRecorder {
+	return sendTo(router, "/api/transactions", key, body, requestID)
+}
+
+func sendTo(router *gin.Engine, path string, key string, body string, requestID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, requestID)
+	if key != "" {
+		req.Header.Set(idempotency.KeyHeader, key)
+	}
+
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestMiddlewareReplaysHeaders(t *testing.T) {
+	router, calls := newRouter(idempotency.NewMemoryStore(), time.Hour, []handlerStep{created})
+
+	first := send(router, "key-1", payload, "request-1")
+	replayed := send(router, "key-1", payload, "request-2")
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, first.Body.String(), replayed.Body.String())
+	assert.Equal(t, "", first.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, "true", replayed.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, `"1"`, replayed.Header().Get("ETag"))
+	assert.Equal(t, "/api/transactions/1", replayed.Header().Get("Location"))
+	assert.Equal(t, first.Header().Get("Content-Type"), replayed.Header().Get("Content-Type"))
+
+	// the request id belongs to the retry, not to the request that was stored
+	assert.Equal(t, "request-2", replayed.Header().Get(middleware.RequestIDHeader))
+}
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		key              string
+		body             string
+		expectedStatus   int
+		expectedReplayed bool
+	}
+
+	testCases := map[string]struct {
+		ttl           time.Duration
+		steps         []handlerStep
+		requests      []request
+		expectedCalls int
+	}{
+		"successfully passed requests without a key": {
+			ttl:   time.Hour,
+			steps: []handlerStep{created, created},
+			requests: []request{
+				{body: payload, expectedStatus: http.StatusCreated},
+				{body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCalls: 2,
+		},
+		"successfully replayed a payload formatted differently": {
+			ttl:   time.Hour,
+			steps: []handlerStep{created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: "{\n  \"userId\": 1,\n  \"amount\": \"1\"\n}", expectedStatus: http.StatusCreated, expectedReplayed: true},
+			},
+			expectedCalls: 1,
+		},
+		"successfully ran again once the key expired": {
+			ttl:   time.Nanosecond,
+			steps: []handlerStep{created, created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCalls: 2,
+		},
+		"successfully retried after a server error": {
+			ttl:   time.Hour,
+			steps: []handlerStep{failed, created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusInternalServerError},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated, expectedReplayed: true},
+			},
+			expectedCalls: 2,
+		},
+		"successfully retried after the handler panicked": {
+			ttl:   time.Hour,
+			steps: []handlerStep{panicked, created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusInternalServerError},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCalls: 2,
+		},
+		"successfully retried after the client went away": {
+			ttl:   time.Hour,
+			steps: []handlerStep{cancelled, created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: apperror.StatusClientClosedRequest},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated, expectedReplayed: true},
+			},
+			expectedCalls: 2,
+		},
+		"error key reused with a different payload": {
+			ttl:   time.Hour,
+			steps: []handlerStep{created},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+				{key: "key-1", body: `{"userId":1,"amount":"2"}`, expectedStatus: http.StatusUnprocessableEntity},
+			},
+			expectedCalls: 1,
+		},
+		"error key reused with a different payload while running": {
+			ttl: time.Hour,
+			steps: []handlerStep{func(c *gin.Context, router *gin.Engine) {
+				// the fingerprint is compared before the request is known to be running
+				retry := send(router, "key-1", `{"userId":2}`, "request-2")
+				c.JSON(http.StatusCreated, gin.H{"retry": retry.Code})
+			}},
+			requests: []request{
+				{key: "key-1", body: payload, expectedStatus: http.StatusCreated},
+			},
+			expectedCalls: 1,
+		},
+		"error key too long": {
+			ttl:   time.Hour,
+			steps: []handlerStep{created},
+			requests: []request{
+				{key: strings.Repeat("k", 256), body: payload, expectedStatus: http.StatusBadRequest},
+			},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			router, calls := newRouter(idempotency.NewMemoryStore(), test.ttl, test.steps)
+
+			for i, r := range test.requests {
+				w := send(router, r.key, r.body, "request-"+strconv.Itoa(i))
+
+				assert.Equal(t, r.expectedStatus, w.Code)
+				assert.Equal(t, r.expectedReplayed, w.Header().Get(idempotency.ReplayedHeader) == "true")
+			}
+
+			assert.Equal(t, test.expectedCalls, *calls)
+		})
+	}
+}
+
+func TestMiddlewareKeyPerPath(t *testing.T) {
+	router, calls := newRouter(idempotency.NewMemoryStore(), time.Hour, []handlerStep{refunded, refunded})
+	refund := `{"amount":"1"}`
+
+	first := sendTo(router, "/api/transactions/1/refunds", "key-1", refund, "request-1")
+	other := sendTo(router, "/api/transactions/2/refunds", "key-1", refund, "request-2")
+	replayed := sendTo(router, "/api/transactions/1/refunds", "key-1", refund, "request-3")
+
+	// the same key and payload on another transaction is another request
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Equal(t, "", other.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, `{"transactionId":"2"}`, other.Body.String())
+	assert.Equal(t, "true", replayed.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, first.Body.String(), replayed.Body.String())
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	retries := map[string]int{}
+	router, calls := newRouter(idempotency.NewMemoryStore(), time.Hour, []handlerStep{
+		func(c *gin.Context, router *gin.Engine) {
+			// retries arriving while the first request still runs are turned away
+			retries["same payload"] = send(router, "key-1", payload, "request-2").Code
+			retries["other payload"] = send(router, "key-1", `{"userId":2}`, "request-3").Code
+			retries["other key"] = send(router, "key-2", payload, "request-4").Code
+			created(c, router)
+		},
+		created,
+	})
+
+	w := send(router, "key-1", payload, "request-1")
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, map[string]int{
+		"same payload":  http.StatusConflict,
+		"other payload": http.StatusUnprocessableEntity,
+		"other key":     http.StatusCreated,
+	}, retries)
+	assert.Equal(t, 2, *calls)
+}
@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+)
+
+// PostgresStore keeps records in the idempotency_keys table, so every instance of the api shares them
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, record Record) (*Record, error) {
+	// the existing record may be released between both statements, the key is then free to take again
+	for {
+		existing, err := s.reserve(ctx, record)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return existing, err
+		}
+	}
+}
+
+func (s *PostgresStore) reserve(ctx context.Context, record Record) (*Record, error) {
+	// take over an expired key in the same statement, so two requests never both get the reservation
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status", "header", "body", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lte{Column: clause.Column{Table: "idempotency_keys", Name: "expires_at"}, Value: record.CreatedAt},
+		}},
+	}).Create(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing Record
+	if err := s.db.WithContext(ctx).Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: record.Key}).First(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	return &existing, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key string, status int, header http.Header, body []byte) error {
+	return s.db.WithContext(ctx).
+		Model(&Record{}).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Updates(map[string]interface{}{"status": status, "header": Header(header), "body": body}).Error
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Delete(&Record{}).Error
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Record is the response stored for an idempotency key, Status is 0 while the first request is running
+type Record struct {
+	Key         string    `gorm:"primaryKey"`
+	Fingerprint string    `gorm:"not null"`
+	Status      int       `gorm:"not null;default:0"`
+	Header      Header    `gorm:"type:jsonb;not null;default:'{}'"`
+	Body        []byte    `gorm:"type:bytea"`
+	CreatedAt   time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+func (r Record) completed() bool {
+	return r.Status != 0
+}
+
+// Store keeps the records of idempotency keys, implementations must be safe for concurrent use
+type Store interface {
+	// Reserve stores record unless an unexpired record with the same key exists, which is returned instead.
+	// A record counts as expired once its ExpiresAt is not after the CreatedAt of the new record
+	Reserve(ctx context.Context, record Record) (*Record, error)
+	// Complete stores the response of the request holding the reservation of key
+	Complete(ctx context.Context, key string, status int, header http.Header, body []byte) error
+	// Release drops the reservation of key, so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Header holds the headers of a stored response, such as its Content-Type and ETag, as a jsonb object of lists
+type Header http.Header
+
+func (h Header) Value() (driver.Value, error) {
+	if h == nil {
+		return "{}", nil
+	}
+
+	value, err := json.Marshal(h)
+	return string(value), err
+}
+
+func (h *Header) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*h = Header{}
+		return nil
+	case []byte:
+		return json.Unmarshal(value, h)
+	case string:
+		return json.Unmarshal([]byte(value), h)
+	}
+
+	return errors.New("header column must be scanned from text")
+}
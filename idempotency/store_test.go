@@ -0,0 +1,205 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	reservation := func(fingerprint string, at time.Time) Record {
+		return Record{Key: "key-1", Fingerprint: fingerprint, CreatedAt: at, ExpiresAt: at.Add(time.Hour)}
+	}
+
+	t.Run("successfully reserved a new key", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		existing, err := store.Reserve(ctx, reservation("a", now))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, existing == nil)
+	})
+
+	t.Run("error key reserved by a running request", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+
+		existing, err := store.Reserve(ctx, reservation("a", now.Add(time.Minute)))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "a", existing.Fingerprint)
+		assert.Equal(t, false, existing.completed())
+	})
+
+	t.Run("error key reserved with another fingerprint", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+
+		existing, err := store.Reserve(ctx, reservation("b", now.Add(time.Minute)))
+
+		// the existing record is returned as is, telling both fingerprints apart is up to the middleware
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "a", existing.Fingerprint)
+	})
+
+	t.Run("successfully returned the completed response", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+		header := http.Header{"Content-Type": {"application/json"}, "Etag": {`"1"`}}
+		body := []byte(`{"id":1}`)
+		assert.Equal(t, nil, store.Complete(ctx, "key-1", http.StatusCreated, header, body))
+
+		// the stored response does not change along with what the caller passed
+		header.Set("Etag", `"2"`)
+		body[0] = '['
+
+		existing, err := store.Reserve(ctx, reservation("a", now.Add(time.Minute)))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, existing.completed())
+		assert.Equal(t, http.StatusCreated, existing.Status)
+		assert.Equal(t, Header{"Content-Type": {"application/json"}, "Etag": {`"1"`}}, existing.Header)
+		assert.Equal(t, `{"id":1}`, string(existing.Body))
+	})
+
+	t.Run("successfully reserved a key again once it expired", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+		_ = store.Complete(ctx, "key-1", http.StatusCreated, nil, nil)
+
+		// expiring exactly when the new request starts counts as expired
+		existing, err := store.Reserve(ctx, reservation("b", now.Add(time.Hour)))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, existing == nil)
+
+		existing, err = store.Reserve(ctx, reservation("c", now.Add(time.Hour+time.Minute)))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "b", existing.Fingerprint)
+		assert.Equal(t, false, existing.completed())
+	})
+
+	t.Run("error key still reserved just before it expires", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+
+		existing, err := store.Reserve(ctx, reservation("b", now.Add(time.Hour-time.Nanosecond)))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "a", existing.Fingerprint)
+	})
+
+	t.Run("successfully reserved a released key", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+		assert.Equal(t, nil, store.Release(ctx, "key-1"))
+
+		existing, err := store.Reserve(ctx, reservation("b", now.Add(time.Minute)))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, existing == nil)
+	})
+
+	t.Run("successfully ignored completing a released key", func(t *testing.T) {
+		store := NewMemoryStore()
+		_, _ = store.Reserve(ctx, reservation("a", now))
+		_ = store.Release(ctx, "key-1")
+
+		assert.Equal(t, nil, store.Complete(ctx, "key-1", http.StatusCreated, nil, nil))
+
+		existing, _ := store.Reserve(ctx, reservation("b", now.Add(time.Minute)))
+		assert.Equal(t, true, existing == nil)
+	})
+}
+
+func TestHeaderColumn(t *testing.T) {
+	value, err := Header{"Etag": {`"1"`}}.Value()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"Etag":["\"1\""]}`, value)
+
+	value, err = Header(nil).Value()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{}", value)
+
+	var header Header
+	assert.Equal(t, nil, header.Scan([]byte(`{"Content-Type":["application/json"]}`)))
+	assert.Equal(t, Header{"Content-Type": {"application/json"}}, header)
+
+	assert.Equal(t, nil, header.Scan(nil))
+	assert.Equal(t, Header{}, header)
+
+	assert.Equal(t, true, header.Scan(1) != nil)
+}
+
+// capturingStore returns a store whose statements are rendered instead of sent, along with the rendered ones
+func capturingStore(t *testing.T) (*PostgresStore, *[]string) {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statements []string
+	capture := func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	}
+	_ = db.Callback().Create().After("gorm:create").Register("test:capture", capture)
+	_ = db.Callback().Query().After("gorm:query").Register("test:capture", capture)
+	_ = db.Callback().Update().After("gorm:update").Register("test:capture", capture)
+	_ = db.Callback().Delete().After("gorm:delete").Register("test:capture", capture)
+
+	return NewPostgresStore(db), &statements
+}
+
+func TestPostgresStoreSQL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("reserve takes over expired keys only", func(t *testing.T) {
+		store, statements := capturingStore(t)
+
+		// nothing is inserted on a dry run, so the existing record is read next
+		_, err := store.reserve(ctx, Record{Key: "key-1", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{
+			`INSERT INTO "idempotency_keys" ("key","fingerprint","status","header","body","created_at","expires_at") VALUES ($1,$2,$3,$4,$5,$6,$7) ` +
+				`ON CONFLICT ("key") DO UPDATE SET "fingerprint"="excluded"."fingerprint","status"="excluded"."status","header"="excluded"."header",` +
+				`"body"="excluded"."body","created_at"="excluded"."created_at","expires_at"="excluded"."expires_at" ` +
+				`WHERE "idempotency_keys"."expires_at" <= $8 `,
+			`SELECT * FROM "idempotency_keys" WHERE "key" = $1 ORDER BY "idempotency_keys"."key" LIMIT $2`,
+		}, *statements)
+	})
+
+	t.Run("complete stores the response of the key", func(t *testing.T) {
+		store, statements := capturingStore(t)
+
+		err := store.Complete(ctx, "key-1", http.StatusCreated, http.Header{"Etag": {`"1"`}}, []byte(`{}`))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{
+			`UPDATE "idempotency_keys" SET "body"=$1,"header"=$2,"status"=$3 WHERE "key" = $4`,
+		}, *statements)
+	})
+
+	t.Run("release deletes the key", func(t *testing.T) {
+		store, statements := capturingStore(t)
+
+		err := store.Release(ctx, "key-1")
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{
+			`DELETE FROM "idempotency_keys" WHERE "key" = $1`,
+		}, *statements)
+	})
+}
@@ -0,0 +1,175 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"go-findest-rest-api/model"
+	"time"
+)
+
+const (
+	JournalPayment = "payment"
+	JournalRefund  = "refund"
+)
+
+// Account holds the postings of one user in one currency, or of the settlement account of a currency when
+// UserID is nil. Code names it, such as user:1:IDR or settlement:IDR
+type Account struct {
+	ID        uint      `gorm:"primaryKey"`
+	Code      string    `gorm:"not null;uniqueIndex"`
+	UserID    *uint     `gorm:"index"`
+	Currency  string    `gorm:"type:char(3);not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (Account) TableName() string {
+	return "ledger_accounts"
+}
+
+// JournalEntry groups the postings of one movement of money, Reference makes posting it a second time a no-op.
+// Its transaction cannot be removed while it exists
+type JournalEntry struct {
+	ID            uint              `gorm:"primaryKey"`
+	Reference     string            `gorm:"not null;uniqueIndex"`
+	Type          string            `gorm:"not null"`
+	TransactionID uint              `gorm:"not null;index"`
+	CreatedAt     time.Time         `gorm:"not null"`
+	Transaction   model.Transaction `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+func (JournalEntry) TableName() string {
+	return "ledger_journal_entries"
+}
+
+// Posting moves Amount in or out of an account, in the minor unit of Currency. Debits are positive and credits
+// negative, so the postings of a journal entry always sum to zero
+type Posting struct {
+	ID             uint         `gorm:"primaryKey"`
+	JournalEntryID uint         `gorm:"not null;index"`
+	AccountID      uint         `gorm:"not null;index"`
+	Amount         int64        `gorm:"not null"`
+	Currency       string       `gorm:"type:char(3);not null"`
+	CreatedAt      time.Time    `gorm:"not null"`
+	JournalEntry   JournalEntry `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Account        Account      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+func (Posting) TableName() string {
+	return "ledger_postings"
+}
+
+// Journal is a journal entry to post along with its lines
+type Journal struct {
+	Reference     string
+	Type          string
+	TransactionID uint
+	Lines         []Line
+}
+
+// Line is a posting to the account named by AccountCode, accounts are opened when they get their first posting
+type Line struct {
+	AccountCode string
+	UserID      *uint
+	Amount      int64
+	Currency    string
+}
+
+// Balance is what a user holds in one currency, credits to the account minus debits from it
+type Balance struct {
+	Currency string
+	Balance  int64
+}
+
+// Unbalanced is a journal entry whose postings in Currency do not sum to zero, or that has no postings at all
+type Unbalanced struct {
+	JournalEntryID uint
+	Reference      string
+	Currency       string
+	Sum            int64
+	Postings       int64
+}
+
+// Report is the outcome of checking every journal entry of the ledger
+type Report struct {
+	Journals   int64
+	Unbalanced []Unbalanced
+}
+
+// Ledger keeps the journals of the money moved by transactions, implementations post within the database
+// transaction running in ctx so journals are committed or rolled back along with the change they record
+type Ledger interface {
+	// Post stores journals, skipping those whose reference was posted already
+	Post(ctx context.Context, journals ...Journal) error
+	// Balances returns the balance of every account of userID, ordered by currency
+	Balances(ctx context.Context, userID uint) ([]Balance, error)
+	// Check lists the journal entries that do not balance
+	Check(ctx context.Context) (*Report, error)
+}
+
+// Payment is the journal of a transaction reaching success, the money lands in the settlement account and is
+// owed to the user. ok is false when after did not just reach success, before is nil for a new transaction
+func Payment(before *model.Transaction, after *model.Transaction) (journal Journal, ok bool) {
+	if after.Status != model.StatusSuccess || (before != nil && before.Status == model.StatusSuccess) {
+		return Journal{}, false
+	}
+
+	return Journal{
+		Reference:     fmt.Sprintf("transaction:%d", after.ID),
+		Type:          JournalPayment,
+		TransactionID: after.ID,
+		Lines: []Line{
+			settlementLine(after.Amount, after.Currency),
+			userLine(after.UserID, -after.Amount, after.Currency),
+		},
+	}, true
+}
+
+// Refund is the journal of a refund of a transaction of userID, it reverses the payment for the refunded amount
+func Refund(refund model.Refund, userID uint) Journal {
+	return Journal{
+		Reference:     fmt.Sprintf("refund:%d", refund.ID),
+		Type:          JournalRefund,
+		TransactionID: refund.TransactionID,
+		Lines: []Line{
+			userLine(userID, refund.Amount, refund.Currency),
+			settlementLine(-refund.Amount, refund.Currency),
+		},
+	}
+}
+
+// SettlementAccount is the code of the account holding the money received in currency
+func SettlementAccount(currency string) string {
+	return "settlement:" + currency
+}
+
+// UserAccount is the code of the account holding what userID is owed in currency
+func UserAccount(userID uint, currency string) string {
+	return fmt.Sprintf("user:%d:%s", userID, currency)
+}
+
+func settlementLine(amount int64, currency string) Line {
+	return Line{AccountCode: SettlementAccount(currency), Amount: amount, Currency: currency}
+}
+
+func userLine(userID uint, amount int64, currency string) Line {
+	return Line{AccountCode: UserAccount(userID, currency), UserID: &userID, Amount: amount, Currency: currency}
+}
+
+// validate refuses journals that would leave the ledger unbalanced
+func (j Journal) validate() error {
+	if len(j.Lines) < 2 {
+		return fmt.Errorf("journal %s needs at least two lines", j.Reference)
+	}
+
+	sums := make(map[string]int64, 1)
+	for _, line := range j.Lines {
+		sums[line.Currency] += line.Amount
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("journal %s does not balance, its %s lines sum to %d", j.Reference, currency, sum)
+		}
+	}
+
+	return nil
+}
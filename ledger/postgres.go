@@ -0,0 +1,149 @@
+package ledger
+
+import (
+	"context"
+	"go-findest-rest-api/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// PostgresLedger keeps the ledger in the ledger_accounts, ledger_journal_entries and ledger_postings tables
+type PostgresLedger struct {
+	db *gorm.DB
+}
+
+func NewPostgresLedger(db *gorm.DB) *PostgresLedger {
+	return &PostgresLedger{
+		db: db,
+	}
+}
+
+func (l *PostgresLedger) Post(ctx context.Context, journals ...Journal) error {
+	for _, journal := range journals {
+		if err := journal.validate(); err != nil {
+			return err
+		}
+	}
+
+	// a savepoint when ctx runs a transaction already, so an entry is never stored without its postings
+	return repository.Conn(ctx, l.db).Transaction(func(tx *gorm.DB) error {
+		accounts := make(map[string]uint)
+		for _, journal := range journals {
+			if err := l.post(tx, journal, accounts); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (l *PostgresLedger) post(tx *gorm.DB, journal Journal, accounts map[string]uint) error {
+	now := time.Now()
+	entry := JournalEntry{
+		Reference:     journal.Reference,
+		Type:          journal.Type,
+		TransactionID: journal.TransactionID,
+		CreatedAt:     now,
+	}
+	result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reference"}},
+		DoNothing: true,
+	}).Create(&entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// posted already
+		return nil
+	}
+
+	postings := make([]Posting, 0, len(journal.Lines))
+	for _, line := range journal.Lines {
+		accountID, err := l.account(tx, line, accounts)
+		if err != nil {
+			return err
+		}
+		postings = append(postings, Posting{
+			JournalEntryID: entry.ID,
+			AccountID:      accountID,
+			Amount:         line.Amount,
+			Currency:       line.Currency,
+			CreatedAt:      now,
+		})
+	}
+
+	return tx.Omit(clause.Associations).Create(&postings).Error
+}
+
+// account returns the id of the account of line, opening it on its first posting
+func (l *PostgresLedger) account(tx *gorm.DB, line Line, accounts map[string]uint) (uint, error) {
+	if id, ok := accounts[line.AccountCode]; ok {
+		return id, nil
+	}
+
+	account := Account{
+		Code:      line.AccountCode,
+		UserID:    line.UserID,
+		Currency:  line.Currency,
+		CreatedAt: time.Now(),
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoNothing: true,
+	}).Create(&account)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := tx.Where(clause.Eq{Column: clause.Column{Name: "code"}, Value: line.AccountCode}).First(&account).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	accounts[line.AccountCode] = account.ID
+	return account.ID, nil
+}
+
+func (l *PostgresLedger) Balances(ctx context.Context, userID uint) ([]Balance, error) {
+	// user accounts are credited when money is owed to the user, so the balance is the negated sum
+	balances := make([]Balance, 0)
+	err := repository.Conn(ctx, l.db).
+		Table("ledger_accounts AS a").
+		Select("a.currency AS currency, CAST(COALESCE(-SUM(p.amount), 0) AS BIGINT) AS balance").
+		Joins("JOIN ledger_postings AS p ON p.account_id = a.id").
+		Where("a.user_id = ?", userID).
+		Group("a.currency").
+		Order("a.currency").
+		Scan(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func (l *PostgresLedger) Check(ctx context.Context) (*Report, error) {
+	db := repository.Conn(ctx, l.db)
+
+	var report Report
+	if err := db.Model(&JournalEntry{}).Count(&report.Journals).Error; err != nil {
+		return nil, err
+	}
+
+	report.Unbalanced = make([]Unbalanced, 0)
+	err := db.
+		Table("ledger_journal_entries AS e").
+		Select("e.id AS journal_entry_id, e.reference, COALESCE(p.currency, '') AS currency, CAST(COALESCE(SUM(p.amount), 0) AS BIGINT) AS sum, COUNT(p.id) AS postings").
+		Joins("LEFT JOIN ledger_postings AS p ON p.journal_entry_id = e.id").
+		Group("e.id, e.reference, p.currency").
+		Having("COALESCE(SUM(p.amount), 0) <> 0 OR COUNT(p.id) = 0").
+		Order("e.id").
+		Scan(&report.Unbalanced).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
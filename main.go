@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go-findest-rest-api/command"
 	"go-findest-rest-api/controller/dashboard_controller"
 	"go-findest-rest-api/controller/transaction_controller"
+	"go-findest-rest-api/controller/user_controller"
 	"go-findest-rest-api/database"
+	"go-findest-rest-api/idempotency"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/middleware"
+	"go-findest-rest-api/migration"
 	"go-findest-rest-api/model"
 	"go-findest-rest-api/repository"
 	"go-findest-rest-api/seeder"
 	"log"
 	"os"
+	"time"
+
+	// embed the time zone database, dashboard time zones must not depend on the host having one
+	_ "time/tzdata"
 )
 
 func main() {
@@ -20,7 +31,35 @@ func main() {
 		log.Fatal("Error loading .env file:", err)
 	}
 
+	// parse per-request query timeout, queries are not bounded when it is empty
+	var queryTimeout time.Duration
+	if value := os.Getenv("QUERY_TIMEOUT"); value != "" {
+		queryTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Error parsing QUERY_TIMEOUT:", err)
+		}
+	}
+
+	// parse how long responses of idempotent requests are replayed
+	idempotencyTTL := 24 * time.Hour
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		idempotencyTTL, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Error parsing IDEMPOTENCY_TTL:", err)
+		}
+	}
+
+	// parse how long deleted transactions are kept before they can be purged
+	deletedRetention := 30 * 24 * time.Hour
+	if value := os.Getenv("DELETED_RETENTION"); value != "" {
+		deletedRetention, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Error parsing DELETED_RETENTION:", err)
+		}
+	}
+
 	r := gin.Default()
+	r.Use(middleware.RequestID(), middleware.Timeout(queryTimeout))
 
 	// initialize database connection
 	database.InitDb(
@@ -32,25 +71,75 @@ func main() {
 	)
 	db := database.Database
 
+	// run a subcommand such as migrate instead of serving the api
+	if len(os.Args) > 1 {
+		config := command.Config{DeletedRetention: deletedRetention}
+		if err := command.Run(db, config, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// sync schema with the models in development, otherwise warn about migrations not applied yet
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		if err := database.AutoMigrate(db); err != nil {
+			log.Fatal("Error auto migrating database:", err)
+		}
+	} else if migrator, err := migration.NewMigrator(db); err == nil {
+		if pending, err := migrator.Pending(context.Background()); err == nil && len(pending) > 0 {
+			log.Printf("Warning: %d pending migration(s), run `go run main.go migrate up`", len(pending))
+		}
+	}
+
 	// seed user into database
 	seeder.SeedUsers(database.Database)
 
 	// create repositories
 	transactionRepo := repository.NewDatabaseRepository[model.Transaction](db)
 	userRepo := repository.NewDatabaseRepository[model.User](db)
+	eventRepo := repository.NewDatabaseRepository[model.TransactionEvent](db)
+	refundRepo := repository.NewDatabaseRepository[model.Refund](db)
+	transactor := repository.NewTransactor(db)
+	ledgerStore := ledger.NewPostgresLedger(db)
+	idempotencyStore := idempotency.NewPostgresStore(db)
 
 	// inject repositories into the controller
-	transactionController := transactioncontroller.NewTransactionController(transactionRepo, userRepo)
+	transactionController := transactioncontroller.NewTransactionController(transactionRepo, userRepo, eventRepo, refundRepo, transactor, ledgerStore, deletedRetention)
 	dashboardController := dashboardcontroller.NewDashboardController(transactionRepo, userRepo)
+	userController := usercontroller.NewUserController(userRepo, transactionRepo, transactor, ledgerStore)
 
 	// routes
-	r.POST("/api/transactions", transactionController.CreateTransaction)
+	r.POST("/api/transactions", idempotency.Middleware(idempotencyStore, idempotencyTTL), transactionController.CreateTransaction)
+	r.POST("/api/transactions/bulk", idempotency.Middleware(idempotencyStore, idempotencyTTL), transactionController.CreateTransactions)
+	r.POST("/api/transactions/bulk-status", transactionController.UpdateTransactionStatuses)
+	r.POST("/api/transactions/bulk-delete", transactionController.DeleteTransactions)
+	r.POST("/api/transactions/import", middleware.NoTimeout(), transactionController.ImportTransactions)
 	r.GET("/api/transactions", transactionController.GetTransactions)
+	r.GET("/api/transactions/statuses", transactionController.GetTransactionStatuses)
+	r.GET("/api/transactions/deleted", transactionController.GetDeletedTransactions)
+	r.GET("/api/transactions/export", middleware.NoTimeout(), transactionController.ExportTransactions)
+	r.POST("/api/transactions/purge", transactionController.PurgeTransactions)
 	r.GET("/api/transactions/:id", transactionController.GetTransactionById)
+	r.GET("/api/transactions/:id/transitions", transactionController.GetTransactionTransitions)
+	r.GET("/api/transactions/:id/history", transactionController.GetTransactionHistory)
+	r.POST("/api/transactions/:id/refunds", idempotency.Middleware(idempotencyStore, idempotencyTTL), transactionController.CreateRefund)
+	r.GET("/api/transactions/:id/refunds", transactionController.GetTransactionRefunds)
 	r.PUT("/api/transactions/:id", transactionController.UpdateTransaction)
+	r.PATCH("/api/transactions/:id", transactionController.PatchTransaction)
 	r.DELETE("/api/transactions/:id", transactionController.DeleteTransaction)
+	r.POST("/api/transactions/:id/restore", transactionController.RestoreTransaction)
 
 	r.GET("/api/dashboard/summary", dashboardController.GetDashboardSummary)
+	r.GET("/api/dashboard/timeseries", dashboardController.GetTimeseries)
+
+	r.POST("/api/users", userController.CreateUser)
+	r.GET("/api/users", userController.GetUsers)
+	r.GET("/api/users/:id", userController.GetUserById)
+	r.GET("/api/users/:id/transactions", transactionController.GetUserTransactions)
+	r.GET("/api/users/:id/summary", userController.GetUserSummary)
+	r.GET("/api/users/:id/balance", userController.GetUserBalance)
+	r.PUT("/api/users/:id", userController.UpdateUser)
+	r.DELETE("/api/users/:id", userController.DeleteUser)
 
 	r.Run()
 }
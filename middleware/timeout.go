@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// untimedContextKey keeps the request context from before Timeout bounded it
+const untimedContextKey = "untimedContext"
+
+// Timeout bounds the context of every request, so queries still running past the timeout are cancelled
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Set(untimedContextKey, c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// NoTimeout lifts the bound set by Timeout for routes that stream for as long as the client keeps reading,
+// the request is still cancelled when the client goes away
+func NoTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ctx, ok := c.Value(untimedContextKey).(context.Context); ok {
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,330 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID serializes migrators running at the same time through a postgres advisory lock
+const lockID = 7130451
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// SchemaMigration is a row of the schema_migrations table, one per applied migration
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in order
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.latest())
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	current, err := m.Current(ctx)
+	if err != nil || current == 0 {
+		return nil, err
+	}
+
+	return m.To(ctx, m.previous(current))
+}
+
+// To applies or rolls back migrations until version is the latest applied one, 0 rolls back everything
+func (m *Migrator) To(ctx context.Context, version int) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	up, down := m.plan(version)
+
+	var done []Migration
+	for _, migration := range up {
+		applied, err := m.run(ctx, migration, true)
+		if err != nil {
+			return done, err
+		}
+		if applied {
+			done = append(done, migration)
+		}
+	}
+
+	for _, migration := range down {
+		rolledBack, err := m.run(ctx, migration, false)
+		if err != nil {
+			return done, err
+		}
+		if rolledBack {
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// Current returns the version of the latest applied migration, 0 when none is applied
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var current int
+	err := m.db.WithContext(ctx).
+		Model(&SchemaMigration{}).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&current).Error
+
+	return current, err
+}
+
+// Status lists every known migration with the time it was applied, nil when pending
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var applied []SchemaMigration
+	if err := m.db.WithContext(ctx).Find(&applied).Error; err != nil {
+		return nil, err
+	}
+
+	return m.statuses(applied), nil
+}
+
+// statuses pairs every known migration with its applied row, rows of unknown versions are left out
+func (m *Migrator) statuses(applied []SchemaMigration) []Status {
+	appliedAt := map[int]time.Time{}
+	for _, a := range applied {
+		appliedAt[a.Version] = a.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// Pending returns the migrations that are not applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// run applies or rolls back a single migration in its own database transaction, it returns false
+// when another migrator got there first
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) (bool, error) {
+	changed := false
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&SchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		script := migration.Up
+		if !up {
+			script = migration.Down
+		}
+
+		// run the script as is, without gorm parsing placeholders out of it
+		if _, err := tx.Statement.ConnPool.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		if up {
+			changed = true
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}
+
+		changed = true
+		return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+	})
+
+	return changed, err
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`).Error
+}
+
+// plan returns the migrations To goes through to reach version, the ones up to version in the order they are
+// applied and the ones above it in the order they are rolled back. run skips those already in place
+func (m *Migrator) plan(version int) (up []Migration, down []Migration) {
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			up = append(up, migration)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if m.migrations[i].Version > version {
+			down = append(down, m.migrations[i])
+		}
+	}
+
+	return up, down
+}
+
+// previous returns the version before current, the one Down rolls back to
+func (m *Migrator) previous(current int) int {
+	previous := 0
+	for _, migration := range m.migrations {
+		if migration.Version < current {
+			previous = migration.Version
+		}
+	}
+
+	return previous
+}
+
+func (m *Migrator) latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+
+	return nil
+}
+
+// load reads the files of the sql directory of fsys named <version>_<name>.up.sql and <version>_<name>.down.sql
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		versionText, name, hasName := strings.Cut(base, "_")
+		version, versionErr := strconv.Atoi(versionText)
+		if !ok || !hasName || versionErr != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("sql", fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		default:
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,253 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/assert/v2"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// migrationFS returns a file system holding an up and a down file for every name, such as 0001_create_users
+func migrationFS(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys["sql/"+name+".up.sql"] = &fstest.MapFile{Data: []byte("-- up " + name)}
+		fsys["sql/"+name+".down.sql"] = &fstest.MapFile{Data: []byte("-- down " + name)}
+	}
+
+	return fsys
+}
+
+func versions(migrations []Migration) []int {
+	result := make([]int, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+
+	return result
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := load(files)
+	assert.Equal(t, nil, err)
+
+	// versions follow each other from 1, and every migration can be rolled back
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version)
+		assert.Equal(t, true, migration.Name != "")
+		assert.Equal(t, true, strings.TrimSpace(migration.Up) != "")
+		assert.Equal(t, true, strings.TrimSpace(migration.Down) != "")
+
+		up, err := files.ReadFile(fmt.Sprintf("sql/%04d_%s.up.sql", migration.Version, migration.Name))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, string(up), migration.Up)
+
+		down, err := files.ReadFile(fmt.Sprintf("sql/%04d_%s.down.sql", migration.Version, migration.Name))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, string(down), migration.Down)
+	}
+
+	assert.Equal(t, true, len(migrations) > 0)
+	assert.Equal(t, "create_users", migrations[0].Name)
+}
+
+func TestLoadOrdering(t *testing.T) {
+	// file names sort 10 before 2, versions are compared as numbers
+	fsys := migrationFS("10_add_index", "2_create_transactions", "0001_create_users", "0003_seed")
+
+	migrations, err := load(fsys)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{1, 2, 3, 10}, versions(migrations))
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "add_index", migrations[3].Name)
+	assert.Equal(t, "-- up 10_add_index", migrations[3].Up)
+	assert.Equal(t, "-- down 10_add_index", migrations[3].Down)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		fsys        fstest.MapFS
+		expectedErr string
+	}{
+		"error missing down file": {
+			fsys: func() fstest.MapFS {
+				fsys := migrationFS("0001_create_users", "0002_create_transactions")
+				delete(fsys, "sql/0002_create_transactions.down.sql")
+				return fsys
+			}(),
+			expectedErr: "migration 0002_create_transactions needs both an up and a down file",
+		},
+		"error missing up file": {
+			fsys: func() fstest.MapFS {
+				fsys := migrationFS("0001_create_users")
+				delete(fsys, "sql/0001_create_users.up.sql")
+				return fsys
+			}(),
+			expectedErr: "migration 0001_create_users needs both an up and a down file",
+		},
+		"error empty down file": {
+			fsys: func() fstest.MapFS {
+				fsys := migrationFS("0001_create_users")
+				fsys["sql/0001_create_users.down.sql"] = &fstest.MapFile{}
+				return fsys
+			}(),
+			expectedErr: "migration 0001_create_users needs both an up and a down file",
+		},
+		"error two names for a version": {
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.up.sql":    {Data: []byte("-- up")},
+				"sql/0001_create_people.down.sql": {Data: []byte("-- down")},
+			},
+			expectedErr: `migration 1 has two names: "create_people" and "create_users"`,
+		},
+		"error name without a version": {
+			fsys:        migrationFS("create_users"),
+			expectedErr: `invalid migration file name "create_users.down.sql"`,
+		},
+		"error version without a name": {
+			fsys:        migrationFS("0001"),
+			expectedErr: `invalid migration file name "0001.down.sql"`,
+		},
+		"error version zero": {
+			fsys:        migrationFS("0000_create_users"),
+			expectedErr: `invalid migration file name "0000_create_users.down.sql"`,
+		},
+		"error negative version": {
+			fsys:        migrationFS("-1_create_users"),
+			expectedErr: `invalid migration file name "-1_create_users.down.sql"`,
+		},
+		"error unknown direction": {
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.sideways.sql": {Data: []byte("-- sideways")},
+			},
+			expectedErr: `invalid migration file name "0001_create_users.sideways.sql"`,
+		},
+		"error file without a direction": {
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.sql": {Data: []byte("-- up")},
+			},
+			expectedErr: `invalid migration file name "0001_create_users.sql"`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			migrations, err := load(test.fsys)
+
+			assert.Equal(t, 0, len(migrations))
+			assert.Equal(t, true, err != nil)
+			if err != nil {
+				assert.Equal(t, test.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadMissingDirectory(t *testing.T) {
+	_, err := load(fstest.MapFS{})
+
+	assert.Equal(t, true, err != nil)
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+
+	migrations, err := load(migrationFS("0001_a", "0002_b", "0003_c", "0005_e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Migrator{migrations: migrations}
+}
+
+func TestPlan(t *testing.T) {
+	testCases := map[string]struct {
+		version      int
+		expectedUp   []int
+		expectedDown []int
+	}{
+		"latest applies everything": {
+			version:      5,
+			expectedUp:   []int{1, 2, 3, 5},
+			expectedDown: []int{},
+		},
+		"version in between": {
+			version:      2,
+			expectedUp:   []int{1, 2},
+			expectedDown: []int{5, 3},
+		},
+		"zero rolls back everything from the latest": {
+			version:      0,
+			expectedUp:   []int{},
+			expectedDown: []int{5, 3, 2, 1},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			up, down := newTestMigrator(t).plan(test.version)
+
+			assert.Equal(t, test.expectedUp, versions(up))
+			assert.Equal(t, test.expectedDown, versions(down))
+		})
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	testCases := map[string]struct {
+		current  int
+		expected int
+	}{
+		"latest":                 {current: 5, expected: 3},
+		"skips a missing number": {current: 3, expected: 2},
+		"first":                  {current: 1, expected: 0},
+		"nothing applied":        {current: 0, expected: 0},
+		"unknown version":        {current: 4, expected: 3},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, newTestMigrator(t).previous(test.current))
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	assert.Equal(t, 5, newTestMigrator(t).latest())
+	assert.Equal(t, 0, (&Migrator{}).latest())
+}
+
+func TestToUnknownVersion(t *testing.T) {
+	// the version is checked before the database is used
+	done, err := newTestMigrator(t).To(context.Background(), 4)
+
+	assert.Equal(t, 0, len(done))
+	assert.Equal(t, true, errors.Is(err, ErrUnknownVersion))
+}
+
+func TestStatuses(t *testing.T) {
+	appliedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	statuses := newTestMigrator(t).statuses([]SchemaMigration{
+		{Version: 1, Name: "a", AppliedAt: appliedAt},
+		{Version: 3, Name: "c", AppliedAt: appliedAt.Add(time.Hour)},
+		{Version: 9, Name: "removed", AppliedAt: appliedAt},
+	})
+
+	assert.Equal(t, []int{1, 2, 3, 5}, func() []int {
+		result := make([]int, 0, len(statuses))
+		for _, status := range statuses {
+			result = append(result, status.Version)
+		}
+		return result
+	}())
+	assert.Equal(t, appliedAt, *statuses[0].AppliedAt)
+	assert.Equal(t, true, statuses[1].AppliedAt == nil)
+	assert.Equal(t, appliedAt.Add(time.Hour), *statuses[2].AppliedAt)
+	assert.Equal(t, true, statuses[3].AppliedAt == nil)
+}
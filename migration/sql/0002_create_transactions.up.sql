@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT,
+    amount     DECIMAL,
+    status     TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    is_deleted BOOLEAN,
+    CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
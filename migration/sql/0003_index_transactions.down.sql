@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_created_at_id;
+DROP INDEX IF EXISTS idx_transactions_status;
+DROP INDEX IF EXISTS idx_transactions_user_id;
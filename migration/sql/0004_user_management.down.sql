@@ -0,0 +1,10 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS fk_transactions_user;
+ALTER TABLE transactions
+    ADD CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS is_deleted,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_transactions_currency;
+
+-- every amount is read back as rupiah, amounts in other currencies cannot be told apart once currency is gone
+ALTER TABLE transactions
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN amount TYPE DECIMAL USING amount / 100.0;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,9 @@
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- amounts were recorded in rupiah, which has two decimals in ISO 4217
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE BIGINT USING ROUND(COALESCE(amount, 0) * 100)::BIGINT,
+    ALTER COLUMN amount SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_currency ON transactions (currency);
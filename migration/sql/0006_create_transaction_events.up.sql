@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS transaction_events (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT      NOT NULL,
+    type           TEXT        NOT NULL,
+    old_values     JSONB,
+    new_values     JSONB,
+    actor          TEXT        NOT NULL,
+    request_id     TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_transaction_events_transaction FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_events_transaction_id ON transaction_events (transaction_id, id);
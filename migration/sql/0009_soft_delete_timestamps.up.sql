@@ -0,0 +1,9 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- rows deleted before the column existed are dated by their last update
+UPDATE transactions SET deleted_at = updated_at WHERE is_deleted AND deleted_at IS NULL;
+UPDATE users SET deleted_at = updated_at WHERE is_deleted AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON transactions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
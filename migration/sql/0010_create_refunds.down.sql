@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS refunds;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS chk_transactions_refunded_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS refunded_amount;
@@ -0,0 +1,19 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
+
+-- refunds used to be recorded by flipping the status only, count those transactions as refunded in full
+UPDATE transactions SET refunded_amount = amount WHERE status = 'refunded';
+
+ALTER TABLE transactions ADD CONSTRAINT chk_transactions_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT      NOT NULL,
+    amount         BIGINT      NOT NULL,
+    currency       CHAR(3)     NOT NULL,
+    reason         TEXT        NOT NULL,
+    actor          TEXT        NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_refunds_transaction FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction_id ON refunds (transaction_id, id);
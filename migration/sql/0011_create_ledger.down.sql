@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_journal_entries;
+DROP TABLE IF EXISTS ledger_accounts;
@@ -0,0 +1,81 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id         BIGSERIAL PRIMARY KEY,
+    code       TEXT        NOT NULL,
+    user_id    BIGINT,
+    currency   CHAR(3)     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_code ON ledger_accounts (code);
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_user_id ON ledger_accounts (user_id);
+
+CREATE TABLE IF NOT EXISTS ledger_journal_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    reference      TEXT        NOT NULL,
+    type           TEXT        NOT NULL,
+    transaction_id BIGINT      NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_journal_entries_reference ON ledger_journal_entries (reference);
+CREATE INDEX IF NOT EXISTS idx_ledger_journal_entries_transaction_id ON ledger_journal_entries (transaction_id);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id               BIGSERIAL PRIMARY KEY,
+    journal_entry_id BIGINT      NOT NULL,
+    account_id       BIGINT      NOT NULL,
+    amount           BIGINT      NOT NULL,
+    currency         CHAR(3)     NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_ledger_postings_journal_entry FOREIGN KEY (journal_entry_id) REFERENCES ledger_journal_entries (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_ledger_postings_account FOREIGN KEY (account_id) REFERENCES ledger_accounts (id) ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_journal_entry_id ON ledger_postings (journal_entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account_id ON ledger_postings (account_id);
+
+-- journals for the money moved before the ledger existed: every transaction that reached success was paid,
+-- deleted ones included since deleting never reverses a payment
+INSERT INTO ledger_accounts (code, user_id, currency, created_at)
+SELECT DISTINCT 'settlement:' || currency, NULL::BIGINT, currency, now()
+FROM transactions WHERE status IN ('success', 'partially_refunded', 'refunded')
+UNION
+SELECT DISTINCT 'user:' || user_id || ':' || currency, user_id, currency, now()
+FROM transactions WHERE status IN ('success', 'partially_refunded', 'refunded')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO ledger_journal_entries (reference, type, transaction_id, created_at)
+SELECT 'transaction:' || id, 'payment', id, updated_at
+FROM transactions WHERE status IN ('success', 'partially_refunded', 'refunded')
+ON CONFLICT (reference) DO NOTHING;
+
+-- refunds recorded as rows of their own, and refunds from before them that only set refunded_amount
+INSERT INTO ledger_journal_entries (reference, type, transaction_id, created_at)
+SELECT 'refund:' || id, 'refund', transaction_id, created_at FROM refunds
+ON CONFLICT (reference) DO NOTHING;
+
+INSERT INTO ledger_journal_entries (reference, type, transaction_id, created_at)
+SELECT 'transaction:' || t.id || ':refund', 'refund', t.id, t.updated_at
+FROM transactions t
+WHERE t.refunded_amount > (SELECT COALESCE(SUM(r.amount), 0) FROM refunds r WHERE r.transaction_id = t.id)
+ON CONFLICT (reference) DO NOTHING;
+
+-- payments debit the settlement account and credit the user, refunds the other way around
+WITH moves AS (
+    SELECT e.id AS journal_entry_id, t.user_id, t.currency, t.amount, e.created_at
+    FROM ledger_journal_entries e JOIN transactions t ON e.reference = 'transaction:' || t.id
+    UNION ALL
+    SELECT e.id, t.user_id, r.currency, -r.amount, e.created_at
+    FROM ledger_journal_entries e
+    JOIN refunds r ON e.reference = 'refund:' || r.id
+    JOIN transactions t ON t.id = r.transaction_id
+    UNION ALL
+    SELECT e.id, t.user_id, t.currency, -(t.refunded_amount - (SELECT COALESCE(SUM(r.amount), 0) FROM refunds r WHERE r.transaction_id = t.id)), e.created_at
+    FROM ledger_journal_entries e JOIN transactions t ON e.reference = 'transaction:' || t.id || ':refund'
+)
+INSERT INTO ledger_postings (journal_entry_id, account_id, amount, currency, created_at)
+SELECT m.journal_entry_id, a.id, m.amount, m.currency, m.created_at
+FROM moves m JOIN ledger_accounts a ON a.code = 'settlement:' || m.currency
+UNION ALL
+SELECT m.journal_entry_id, a.id, -m.amount, m.currency, m.created_at
+FROM moves m JOIN ledger_accounts a ON a.code = 'user:' || m.user_id || ':' || m.currency;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_tags;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS tags;
+ALTER TABLE transactions DROP COLUMN IF EXISTS metadata;
+ALTER TABLE transactions DROP COLUMN IF EXISTS description;
@@ -0,0 +1,6 @@
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT '';
+
+UPDATE idempotency_keys
+SET content_type = COALESCE(header -> 'Content-Type' ->> 0, '');
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS header;
@@ -0,0 +1,8 @@
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS header JSONB NOT NULL DEFAULT '{}';
+
+-- stored responses only kept their content type so far
+UPDATE idempotency_keys
+SET header = jsonb_build_object('Content-Type', jsonb_build_array(content_type))
+WHERE content_type <> '';
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS content_type;
@@ -0,0 +1 @@
+ALTER TABLE ledger_journal_entries DROP CONSTRAINT IF EXISTS fk_ledger_journal_entries_transaction;
@@ -0,0 +1,4 @@
+-- a journal records money a transaction moved, so the transaction cannot be removed while its journals exist.
+-- NOT VALID skips checking journals whose transaction was purged before this migration, new ones are checked
+ALTER TABLE ledger_journal_entries
+    ADD CONSTRAINT fk_ledger_journal_entries_transaction FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON UPDATE CASCADE ON DELETE RESTRICT NOT VALID;
@@ -1,42 +1,71 @@
 package mock
 
 import (
+	"context"
 	"github.com/stretchr/testify/mock"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/repository"
+	"time"
 )
 
 type MockDatabaseRepository[T any] struct {
 	mock.Mock
 }
 
-func (m *MockDatabaseRepository[T]) First(conds ...interface{}) (*T, error) {
-	args := m.Called(conds...)
+func (m *MockDatabaseRepository[T]) First(ctx context.Context, id interface{}) (*T, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*T), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockDatabaseRepository[T]) Create(value *T) (*T, error) {
-	args := m.Called(value)
+func (m *MockDatabaseRepository[T]) Create(ctx context.Context, value *T) (*T, error) {
+	args := m.Called(ctx, value)
 	return args.Get(0).(*T), args.Error(1)
 }
 
-func (m *MockDatabaseRepository[T]) Find(filter string) ([]T, error) {
-	args := m.Called(filter)
+func (m *MockDatabaseRepository[T]) CreateInBatches(ctx context.Context, values []T, batchSize int) ([]T, error) {
+	args := m.Called(ctx, values, batchSize)
 	if args.Get(0) != nil {
 		return args.Get(0).([]T), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockDatabaseRepository[T]) Save(value interface{}, conds ...interface{}) (*T, error) {
-	args := m.Called(value)
+func (m *MockDatabaseRepository[T]) Find(ctx context.Context, filter repository.Filter) ([]T, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]T), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// Each hands the rows of the first return value to fn, then returns the second
+func (m *MockDatabaseRepository[T]) Each(ctx context.Context, filter repository.Filter, fn func(value *T) error) error {
+	args := m.Called(ctx, filter)
+	if rows, ok := args.Get(0).([]T); ok {
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockDatabaseRepository[T]) Count(ctx context.Context, filter repository.Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDatabaseRepository[T]) Save(ctx context.Context, value *T, id interface{}) (*T, error) {
+	args := m.Called(ctx, value)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 
-	args = m.Called(conds)
+	args = m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -44,10 +73,31 @@ func (m *MockDatabaseRepository[T]) Save(value interface{}, conds ...interface{}
 	return args.Get(0).(*T), args.Error(1)
 }
 
-func (m *MockDatabaseRepository[T]) AverageTransaction() ([]dto.AverageTransactionAttr, error) {
-	args := m.Called()
+func (m *MockDatabaseRepository[T]) UpdateAll(ctx context.Context, filter repository.Filter, values map[string]interface{}) ([]T, error) {
+	args := m.Called(ctx, filter, values)
+	if args.Get(0) != nil {
+		return args.Get(0).([]T), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockDatabaseRepository[T]) Stats(ctx context.Context, filter repository.Filter, aggregate repository.Aggregate) ([]dto.TransactionStatsAttr, error) {
+	args := m.Called(ctx, filter, aggregate)
+	if args.Get(0) != nil {
+		return args.Get(0).([]dto.TransactionStatsAttr), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockDatabaseRepository[T]) Series(ctx context.Context, filter repository.Filter, bucket repository.Bucket, aggregate repository.Aggregate) ([]dto.TransactionSeriesAttr, error) {
+	args := m.Called(ctx, filter, bucket, aggregate)
 	if args.Get(0) != nil {
-		return args.Get(0).([]dto.AverageTransactionAttr), args.Error(1)
+		return args.Get(0).([]dto.TransactionSeriesAttr), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
+
+func (m *MockDatabaseRepository[T]) Purge(ctx context.Context, deletedBefore time.Time, filter repository.Filter) (int64, error) {
+	args := m.Called(ctx, deletedBefore, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,32 @@
+package mock
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-findest-rest-api/ledger"
+)
+
+type MockLedger struct {
+	mock.Mock
+}
+
+func (m *MockLedger) Post(ctx context.Context, journals ...ledger.Journal) error {
+	args := m.Called(ctx, journals)
+	return args.Error(0)
+}
+
+func (m *MockLedger) Balances(ctx context.Context, userID uint) ([]ledger.Balance, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]ledger.Balance), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLedger) Check(ctx context.Context) (*ledger.Report, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(*ledger.Report), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
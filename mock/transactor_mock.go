@@ -0,0 +1,12 @@
+package mock
+
+import (
+	"context"
+)
+
+// MockTransactor runs the work right away without a database transaction, so nothing is rolled back
+type MockTransactor struct{}
+
+func (m *MockTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
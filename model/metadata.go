@@ -0,0 +1,68 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Metadata is a flat object of strings, numbers and booleans stored in a jsonb column. Numbers are kept as
+// json.Number so ids longer than a float64 survive a round trip
+type Metadata map[string]any
+
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+
+	value, err := json.Marshal(map[string]any(m))
+	return string(value), err
+}
+
+func (m *Metadata) Scan(src interface{}) error {
+	raw, err := jsonSource(src)
+	if err != nil || raw == nil {
+		*m = nil
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(m)
+}
+
+// Tags is a list of tags stored as a jsonb array, so transactions can be matched by one of them
+type Tags []string
+
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+
+	value, err := json.Marshal([]string(t))
+	return string(value), err
+}
+
+func (t *Tags) Scan(src interface{}) error {
+	raw, err := jsonSource(src)
+	if err != nil || raw == nil {
+		*t = nil
+		return err
+	}
+
+	return json.Unmarshal(raw, t)
+}
+
+func jsonSource(src interface{}) ([]byte, error) {
+	switch value := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	}
+
+	return nil, errors.New("json column must be scanned from text")
+}
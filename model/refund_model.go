@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Refund gives back Amount of a transaction, in the minor unit of the transaction currency
+type Refund struct {
+	ID            uint        `json:"id" gorm:"primaryKey;index:idx_refunds_transaction_id,priority:2"`
+	TransactionID uint        `json:"transactionId" gorm:"not null;index:idx_refunds_transaction_id,priority:1"`
+	Amount        int64       `json:"amount" gorm:"not null"`
+	Currency      string      `json:"currency" gorm:"type:char(3);not null"`
+	Reason        string      `json:"reason" gorm:"not null"`
+	Actor         string      `json:"actor" gorm:"not null"`
+	CreatedAt     time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+	Transaction   Transaction `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
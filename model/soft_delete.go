@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// SoftDeletable is implemented by models whose rows are flagged as deleted instead of being removed
+type SoftDeletable interface {
+	SoftDeleteColumn() string
+	DeletedAtColumn() string
+}
+
+// SoftDelete opts a model into soft delete when embedded, DeletedAt is set whenever IsDeleted is
+type SoftDelete struct {
+	IsDeleted bool       `json:"isDeleted"`
+	DeletedAt *time.Time `json:"deletedAt" gorm:"index"`
+}
+
+// Deleted flags a row as deleted at the given time
+func Deleted(at time.Time) SoftDelete {
+	return SoftDelete{IsDeleted: true, DeletedAt: &at}
+}
+
+func (SoftDelete) SoftDeleteColumn() string {
+	return "is_deleted"
+}
+
+func (SoftDelete) DeletedAtColumn() string {
+	return "deleted_at"
+}
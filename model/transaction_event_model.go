@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	EventCreated       = "created"
+	EventStatusChanged = "status_changed"
+	EventUpdated       = "updated"
+	EventDeleted       = "deleted"
+	EventRestored      = "restored"
+	EventRefunded      = "refunded"
+)
+
+// TransactionEvent records one change of a transaction, OldValues is empty for created events
+type TransactionEvent struct {
+	ID            uint        `json:"id" gorm:"primaryKey;index:idx_transaction_events_transaction_id,priority:2"`
+	TransactionID uint        `json:"transactionId" gorm:"not null;index:idx_transaction_events_transaction_id,priority:1"`
+	Type          string      `json:"type" gorm:"not null"`
+	OldValues     JSON        `json:"oldValues" gorm:"type:jsonb"`
+	NewValues     JSON        `json:"newValues" gorm:"type:jsonb"`
+	Actor         string      `json:"actor" gorm:"not null"`
+	RequestID     string      `json:"requestId" gorm:"not null;default:''"`
+	CreatedAt     time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+	Transaction   Transaction `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
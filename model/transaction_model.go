@@ -2,13 +2,25 @@ package model
 
 import "time"
 
+// Transaction holds Amount in the minor unit of Currency, such as cents or sen, so it is always exact.
+// RefundedAmount is the total of its refunds in the same unit. Description, Metadata and Tags are set by clients
+// to link the transaction to their own records, such as an order id
 type Transaction struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"userId"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	IsDeleted bool      `json:"isDeleted"`
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
-	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ID             uint      `json:"id" gorm:"primaryKey;index:idx_transactions_created_at_id,priority:2"`
+	UserID         uint      `json:"userId" gorm:"index"`
+	Amount         int64     `json:"amount" gorm:"not null"`
+	Currency       string    `json:"currency" gorm:"type:char(3);not null;default:IDR;index"`
+	RefundedAmount int64     `json:"refundedAmount" gorm:"not null;default:0;check:chk_transactions_refunded_amount,refunded_amount >= 0 AND refunded_amount <= amount"`
+	Status         string    `json:"status" gorm:"index"`
+	Description    string    `json:"description" gorm:"not null;default:''"`
+	Metadata       Metadata  `json:"metadata" gorm:"type:jsonb;not null;default:'{}'"`
+	Tags           Tags      `json:"tags" gorm:"type:jsonb;not null;default:'[]';index:idx_transactions_tags,type:gin"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_transactions_created_at_id,priority:1"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	User           User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SoftDelete
+	Version
 }
+
+// TransactionNetAmount is the amount of a transaction that was not refunded, the one its stats add up
+const TransactionNetAmount = "amount - refunded_amount"
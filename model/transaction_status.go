@@ -0,0 +1,125 @@
+package model
+
+const (
+	StatusPending   = "pending"
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	StatusRefunded  = "refunded"
+
+	// StatusPartiallyRefunded and StatusRefunded are only reached by refunding a transaction
+	StatusPartiallyRefunded = "partially_refunded"
+)
+
+// StatusRule declares a status, whether a transaction may be created in it, the statuses it may move to and
+// whether it can be refunded
+type StatusRule struct {
+	Status      string
+	Initial     bool
+	Transitions []string
+	Refundable  bool
+}
+
+// statusRules is the transaction state machine, a status without transitions that cannot be refunded is final
+var statusRules = []StatusRule{
+	{Status: StatusPending, Initial: true, Transitions: []string{StatusSuccess, StatusFailed, StatusCancelled, StatusExpired}},
+	{Status: StatusSuccess, Initial: true, Refundable: true},
+	{Status: StatusFailed, Initial: true},
+	{Status: StatusCancelled},
+	{Status: StatusExpired},
+	{Status: StatusPartiallyRefunded, Refundable: true},
+	{Status: StatusRefunded},
+}
+
+// StatusRules returns a copy of the state machine in declaration order
+func StatusRules() []StatusRule {
+	rules := make([]StatusRule, 0, len(statusRules))
+	for _, rule := range statusRules {
+		rule.Transitions = append([]string{}, rule.Transitions...)
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func IsValidStatus(status string) bool {
+	_, ok := statusRule(status)
+	return ok
+}
+
+// IsInitialStatus reports whether a transaction may be created in status
+func IsInitialStatus(status string) bool {
+	rule, ok := statusRule(status)
+	return ok && rule.Initial
+}
+
+// IsRefundable reports whether a transaction in status may be refunded
+func IsRefundable(status string) bool {
+	rule, ok := statusRule(status)
+	return ok && rule.Refundable
+}
+
+// IsRefundedStatus reports whether status is only reached by refunding a transaction
+func IsRefundedStatus(status string) bool {
+	return status == StatusPartiallyRefunded || status == StatusRefunded
+}
+
+// IsSettledStatus reports whether a transaction in status was paid, its payment journal is in the ledger so the
+// transaction must be kept
+func IsSettledStatus(status string) bool {
+	return status == StatusSuccess || IsRefundedStatus(status)
+}
+
+// SettledStatuses lists the statuses IsSettledStatus reports
+func SettledStatuses() []string {
+	return []string{StatusSuccess, StatusPartiallyRefunded, StatusRefunded}
+}
+
+// RefundedStatus is the status of a refundable transaction of amount once refunded in total
+func RefundedStatus(amount int64, refunded int64) string {
+	if refunded >= amount {
+		return StatusRefunded
+	}
+
+	return StatusPartiallyRefunded
+}
+
+// Transitions lists the statuses a transaction in status may move to
+func Transitions(status string) []string {
+	rule, _ := statusRule(status)
+	return append([]string{}, rule.Transitions...)
+}
+
+// SourceStatuses lists the statuses a transaction may move to status from
+func SourceStatuses(status string) []string {
+	var sources []string
+	for _, rule := range statusRules {
+		if CanTransition(rule.Status, status) {
+			sources = append(sources, rule.Status)
+		}
+	}
+
+	return sources
+}
+
+func CanTransition(from string, to string) bool {
+	rule, _ := statusRule(from)
+	for _, status := range rule.Transitions {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+func statusRule(status string) (StatusRule, bool) {
+	for _, rule := range statusRules {
+		if rule.Status == status {
+			return rule, true
+		}
+	}
+
+	return StatusRule{}, false
+}
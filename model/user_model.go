@@ -1,6 +1,11 @@
 package model
 
+import "time"
+
 type User struct {
-	ID   uint   `json:"id" gorm:"primaryKey"`
-	Name string `json:"name"`
+	ID        uint      `json:"id" gorm:"primaryKey;index:idx_users_created_at_id,priority:2"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_users_created_at_id,priority:1"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	SoftDelete
 }
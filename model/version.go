@@ -0,0 +1,26 @@
+package model
+
+// Versioned is implemented by models whose rows carry a version, saving them only succeeds when the row
+// still has the version that was read
+type Versioned interface {
+	VersionColumn() string
+	CurrentVersion() uint
+	SetVersion(version uint)
+}
+
+// Version opts a model into optimistic locking when embedded, new rows start at version 1
+type Version struct {
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+func (Version) VersionColumn() string {
+	return "version"
+}
+
+func (v Version) CurrentVersion() uint {
+	return v.Version
+}
+
+func (v *Version) SetVersion(version uint) {
+	v.Version = version
+}
@@ -0,0 +1,122 @@
+package money
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrTooPrecise      = errors.New("amount has more decimals than its currency allows")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Currency is an ISO 4217 currency, Exponent is the number of decimals of its minor unit
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// currencies lists the ISO 4217 currencies accepted by the api
+var currencies = map[string]Currency{}
+
+func init() {
+	exponents := map[int][]string{
+		0: {"BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG", "RWF", "UGX", "VND", "VUV", "XAF", "XOF", "XPF"},
+		2: {
+			"AED", "ARS", "AUD", "BDT", "BRL", "CAD", "CHF", "CNY", "CZK", "DKK", "EGP", "EUR", "GBP", "HKD",
+			"HUF", "IDR", "ILS", "INR", "KES", "LKR", "MXN", "MYR", "NGN", "NOK", "NZD", "PHP", "PKR", "PLN",
+			"RON", "RUB", "SAR", "SEK", "SGD", "THB", "TRY", "TWD", "UAH", "USD", "ZAR",
+		},
+		3: {"BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND"},
+	}
+	for exponent, codes := range exponents {
+		for _, code := range codes {
+			currencies[code] = Currency{Code: code, Exponent: exponent}
+		}
+	}
+}
+
+// Lookup finds a currency by its ISO 4217 code, ignoring case
+func Lookup(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, ErrUnknownCurrency
+	}
+
+	return currency, nil
+}
+
+// Parse reads a decimal such as "1250.50" into minor units of c without going through a float
+func (c Currency) Parse(value string) (int64, error) {
+	if !decimalPattern.MatchString(value) {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+
+	// trailing zeros do not add precision, 100.50 is a valid IDR amount
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > c.Exponent {
+		return 0, ErrTooPrecise
+	}
+	fraction += strings.Repeat("0", c.Exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return minor, nil
+}
+
+// Format writes minor units of c as a decimal with exactly Exponent decimals
+func (c Currency) Format(minor int64) string {
+	sign := ""
+	digits := strconv.FormatUint(uint64(minor), 10)
+	if minor < 0 {
+		sign = "-"
+		digits = strconv.FormatUint(uint64(-(minor+1))+1, 10)
+	}
+	if c.Exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= c.Exponent {
+		digits = strings.Repeat("0", c.Exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - c.Exponent
+
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Money is an amount in minor units of a currency, it is encoded in JSON as an exact decimal string
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+func (m Money) String() string {
+	currency, err := Lookup(m.Currency)
+	if err != nil {
+		return strconv.FormatInt(m.Minor, 10)
+	}
+
+	return currency.Format(m.Minor)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
@@ -0,0 +1,202 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/repository"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrConflictingCursor = errors.New("after and before cannot be used together")
+	ErrCursorSort        = errors.New("after and before only support the default sort")
+)
+
+// Cursor points at a row in a listing ordered by (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uint      `json:"id"`
+}
+
+// Encode returns the cursor as an opaque string that is safe to put in a query string
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// Page is the slice of a listing a client asked for, either a page number or a cursor to continue from
+type Page struct {
+	Number int
+	Size   int
+	After  *Cursor
+	Before *Cursor
+	Sort   []repository.Sort
+}
+
+func NewPage(page int, pageSize int, after string, before string) (Page, error) {
+	page, pageSize = Normalize(page, pageSize)
+	if after != "" && before != "" {
+		return Page{}, queryError("before", "conflicting_cursor", ErrConflictingCursor)
+	}
+
+	if after != "" {
+		cursor, err := DecodeCursor(after)
+		if err != nil {
+			return Page{}, queryError("after", "invalid_cursor", err)
+		}
+
+		return Page{Size: pageSize, After: &cursor}, nil
+	}
+
+	if before != "" {
+		cursor, err := DecodeCursor(before)
+		if err != nil {
+			return Page{}, queryError("before", "invalid_cursor", err)
+		}
+
+		return Page{Size: pageSize, Before: &cursor}, nil
+	}
+
+	return Page{Number: page, Size: pageSize}, nil
+}
+
+// Sorted replaces the default Newest order, which is only possible when paging by number
+func (p Page) Sorted(sort []repository.Sort) (Page, error) {
+	if len(sort) == 0 {
+		return p, nil
+	}
+
+	if p.After != nil || p.Before != nil {
+		return p, queryError("sort", "cursor_sort", ErrCursorSort)
+	}
+
+	p.Sort = sort
+	return p, nil
+}
+
+// Filter narrows where down to the page, it asks for one extra row so Rows can tell whether more follow
+func (p Page) Filter(where repository.Expression) repository.Filter {
+	switch {
+	case p.After != nil:
+		return repository.Filter{Where: repository.All(where, After(*p.After)), Sort: Newest(), Limit: p.Size + 1}
+	case p.Before != nil:
+		return repository.Filter{Where: repository.All(where, Before(*p.Before)), Sort: Oldest(), Limit: p.Size + 1}
+	}
+
+	sort := p.Sort
+	if len(sort) == 0 {
+		sort = Newest()
+	}
+
+	return repository.Filter{Where: where, Sort: sort, Limit: p.Size + 1, Offset: (p.Number - 1) * p.Size}
+}
+
+// Rows trims the rows fetched with Filter to the page in Newest order and returns the cursors around it
+func Rows[T any](p Page, rows []T, cursorOf func(T) Cursor) ([]T, string, string) {
+	hasMore := len(rows) > p.Size
+	if hasMore {
+		rows = rows[:p.Size]
+	}
+
+	if p.Before != nil {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	// cursors only follow the Newest order
+	if len(rows) == 0 || len(p.Sort) > 0 {
+		return rows, "", ""
+	}
+
+	var next, prev string
+	if hasMore || p.Before != nil {
+		next = cursorOf(rows[len(rows)-1]).Encode()
+	}
+	if (hasMore && p.Before != nil) || p.After != nil || p.Number > 1 {
+		prev = cursorOf(rows[0]).Encode()
+	}
+
+	return rows, next, prev
+}
+
+// Normalize falls back to the first page and default size, and caps the size at MaxPageSize
+func Normalize(page int, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	return page, pageSize
+}
+
+// Newest orders a listing from the newest row to the oldest, which is the order cursors walk through
+func Newest() []repository.Sort {
+	return []repository.Sort{
+		{Field: "created_at", Descending: true},
+		{Field: "id", Descending: true},
+	}
+}
+
+// Oldest is the reverse of Newest, used to read the page before a cursor
+func Oldest() []repository.Sort {
+	return []repository.Sort{
+		{Field: "created_at"},
+		{Field: "id"},
+	}
+}
+
+// After matches the rows that come after the cursor in Newest order
+func After(c Cursor) repository.Expression {
+	return repository.Any(
+		repository.Lt("created_at", c.CreatedAt),
+		repository.All(
+			repository.Eq("created_at", c.CreatedAt),
+			repository.Lt("id", c.ID),
+		),
+	)
+}
+
+// Before matches the rows that come before the cursor in Newest order
+func Before(c Cursor) repository.Expression {
+	return repository.Any(
+		repository.Gt("created_at", c.CreatedAt),
+		repository.All(
+			repository.Eq("created_at", c.CreatedAt),
+			repository.Gt("id", c.ID),
+		),
+	)
+}
+
+func queryError(field string, code string, err error) error {
+	return apperror.InvalidQuery(apperror.FieldError{Field: field, Code: code, Message: err.Error()}).Wrap(err)
+}
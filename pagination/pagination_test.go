@@ -0,0 +1,484 @@
+package pagination_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/pagination"
+	"go-findest-rest-api/repository"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID        uint
+	CreatedAt time.Time
+}
+
+func rowCursor(r row) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// find runs filter against rows the way the repository does, for the fields and operators pages use
+func find(rows []row, filter repository.Filter) []row {
+	found := make([]row, 0, len(rows))
+	for _, r := range rows {
+		if matches(r, filter.Where) {
+			found = append(found, r)
+		}
+	}
+
+	slices.SortStableFunc(found, func(a row, b row) int {
+		for _, s := range filter.Sort {
+			c := compare(a, s.Field, fieldOf(b, s.Field))
+			if s.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	})
+
+	if filter.Offset >= len(found) {
+		return nil
+	}
+	found = found[filter.Offset:]
+	if filter.Limit > 0 && len(found) > filter.Limit {
+		found = found[:filter.Limit]
+	}
+
+	return found
+}
+
+func matches(r row, expression repository.Expression) bool {
+	switch e := expression.(type) {
+	case nil:
+		return true
+	case repository.Group:
+		any := e.Match == repository.MatchAny
+		matched := 0
+		for _, sub := range e.Expressions {
+			if sub == nil {
+				continue
+			}
+			if matches(r, sub) {
+				if any {
+					return true
+				}
+				matched++
+			} else if !any {
+				return false
+			}
+		}
+
+		return !any || matched > 0
+	case repository.Condition:
+		c := compare(r, e.Field, e.Value)
+		switch e.Operator {
+		case repository.OpEqual:
+			return c == 0
+		case repository.OpLessThan:
+			return c < 0
+		case repository.OpGreaterThan:
+			return c > 0
+		}
+	}
+
+	panic("unsupported expression")
+}
+
+func fieldOf(r row, field string) interface{} {
+	switch field {
+	case "id":
+		return r.ID
+	case "created_at":
+		return r.CreatedAt
+	}
+
+	panic("unsupported field " + field)
+}
+
+func compare(r row, field string, value interface{}) int {
+	switch field {
+	case "id":
+		id := value.(uint)
+		switch {
+		case r.ID < id:
+			return -1
+		case r.ID > id:
+			return 1
+		}
+		return 0
+	case "created_at":
+		return r.CreatedAt.Compare(value.(time.Time))
+	}
+
+	panic("unsupported field " + field)
+}
+
+func ids(rows []row) []uint {
+	result := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, r.ID)
+	}
+
+	return result
+}
+
+// rows share created_at in groups, so only the id keeps their order apart
+func tiedRows() []row {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t0.Add(2 * time.Hour)
+
+	return []row{
+		{ID: 1, CreatedAt: t1},
+		{ID: 2, CreatedAt: t0},
+		{ID: 3, CreatedAt: t1},
+		{ID: 4, CreatedAt: t1},
+		{ID: 5, CreatedAt: t2},
+		{ID: 6, CreatedAt: t0},
+		{ID: 7, CreatedAt: t2},
+		{ID: 8, CreatedAt: t1},
+	}
+}
+
+// newest is the order of tiedRows by created_at then id, both descending
+var newest = []uint{7, 5, 8, 4, 3, 1, 6, 2}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pagination.Cursor{
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.FixedZone("WIB", 7*60*60)),
+		ID:        42,
+	}
+
+	decoded, err := pagination.DecodeCursor(cursor.Encode())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, cursor.ID, decoded.ID)
+	assert.Equal(t, true, cursor.CreatedAt.Equal(decoded.CreatedAt))
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	valid := pagination.Cursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: 42}.Encode()
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	testCases := map[string]struct {
+		cursor string
+	}{
+		"error not base64":             {cursor: "not a cursor!"},
+		"error padded base64":          {cursor: base64.URLEncoding.EncodeToString([]byte(`{"createdAt":"2025-01-02T03:04:05Z","id":1}`)) + "="},
+		"error standard base64":        {cursor: "+/" + valid},
+		"error truncated":              {cursor: valid[:len(valid)-3]},
+		"error extra characters":       {cursor: valid + "AAAA"},
+		"error not json":               {cursor: encode("createdAt=2025-01-02&id=1")},
+		"error json array":             {cursor: encode(`[1]`)},
+		"error missing id":             {cursor: encode(`{"createdAt":"2025-01-02T03:04:05Z"}`)},
+		"error zero id":                {cursor: encode(`{"createdAt":"2025-01-02T03:04:05Z","id":0}`)},
+		"error negative id":            {cursor: encode(`{"createdAt":"2025-01-02T03:04:05Z","id":-1}`)},
+		"error id as string":           {cursor: encode(`{"createdAt":"2025-01-02T03:04:05Z","id":"1"}`)},
+		"error created at not a time":  {cursor: encode(`{"createdAt":"yesterday","id":1}`)},
+		"error created at as a number": {cursor: encode(`{"createdAt":1735787045,"id":1}`)},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := pagination.DecodeCursor(test.cursor)
+
+			assert.Equal(t, pagination.ErrInvalidCursor, err)
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursor := pagination.Cursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: 42}
+
+	testCases := map[string]struct {
+		page          int
+		pageSize      int
+		after         string
+		before        string
+		expectedPage  pagination.Page
+		expectedErr   error
+		expectedField string
+		expectedCode  string
+	}{
+		"default page": {
+			expectedPage: pagination.Page{Number: 1, Size: pagination.DefaultPageSize},
+		},
+		"page number": {
+			page:         3,
+			pageSize:     10,
+			expectedPage: pagination.Page{Number: 3, Size: 10},
+		},
+		"size capped": {
+			page:         1,
+			pageSize:     pagination.MaxPageSize + 1,
+			expectedPage: pagination.Page{Number: 1, Size: pagination.MaxPageSize},
+		},
+		"after cursor": {
+			page:         3,
+			pageSize:     10,
+			after:        cursor.Encode(),
+			expectedPage: pagination.Page{Size: 10, After: &cursor},
+		},
+		"before cursor": {
+			pageSize:     10,
+			before:       cursor.Encode(),
+			expectedPage: pagination.Page{Size: 10, Before: &cursor},
+		},
+		"error after and before": {
+			after:         cursor.Encode(),
+			before:        cursor.Encode(),
+			expectedErr:   pagination.ErrConflictingCursor,
+			expectedField: "before",
+			expectedCode:  "conflicting_cursor",
+		},
+		"error invalid after": {
+			after:         cursor.Encode() + "x",
+			expectedErr:   pagination.ErrInvalidCursor,
+			expectedField: "after",
+			expectedCode:  "invalid_cursor",
+		},
+		"error invalid before": {
+			before:        "x",
+			expectedErr:   pagination.ErrInvalidCursor,
+			expectedField: "before",
+			expectedCode:  "invalid_cursor",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			page, err := pagination.NewPage(test.page, test.pageSize, test.after, test.before)
+
+			if test.expectedErr != nil {
+				var appErr *apperror.Error
+				assert.Equal(t, true, errors.As(err, &appErr))
+				assert.Equal(t, true, errors.Is(err, test.expectedErr))
+				assert.Equal(t, http.StatusBadRequest, appErr.Status)
+				assert.Equal(t, []apperror.FieldError{{
+					Field:   test.expectedField,
+					Code:    test.expectedCode,
+					Message: test.expectedErr.Error(),
+				}}, appErr.Fields)
+				return
+			}
+
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.expectedPage.Number, page.Number)
+			assert.Equal(t, test.expectedPage.Size, page.Size)
+			assert.Equal(t, test.expectedPage.After == nil, page.After == nil)
+			assert.Equal(t, test.expectedPage.Before == nil, page.Before == nil)
+			if page.After != nil {
+				assert.Equal(t, test.expectedPage.After.ID, page.After.ID)
+				assert.Equal(t, true, test.expectedPage.After.CreatedAt.Equal(page.After.CreatedAt))
+			}
+			if page.Before != nil {
+				assert.Equal(t, test.expectedPage.Before.ID, page.Before.ID)
+				assert.Equal(t, true, test.expectedPage.Before.CreatedAt.Equal(page.Before.CreatedAt))
+			}
+		})
+	}
+}
+
+func TestPageSortedWithCursor(t *testing.T) {
+	cursor := pagination.Cursor{CreatedAt: time.Now(), ID: 1}
+	sort := []repository.Sort{{Field: "amount"}}
+
+	_, err := pagination.Page{Size: 10, After: &cursor}.Sorted(sort)
+	assert.Equal(t, true, errors.Is(err, pagination.ErrCursorSort))
+
+	_, err = pagination.Page{Size: 10, Before: &cursor}.Sorted(sort)
+	assert.Equal(t, true, errors.Is(err, pagination.ErrCursorSort))
+
+	page, err := pagination.Page{Number: 2, Size: 10}.Sorted(sort)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, repository.Filter{Sort: sort, Limit: 11, Offset: 10}, page.Filter(nil))
+}
+
+func TestPageFilter(t *testing.T) {
+	cursor := pagination.Cursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: 42}
+	where := repository.Eq("user_id", uint(1))
+
+	testCases := map[string]struct {
+		page           pagination.Page
+		expectedFilter repository.Filter
+	}{
+		"page number": {
+			page: pagination.Page{Number: 3, Size: 10},
+			expectedFilter: repository.Filter{
+				Where:  where,
+				Sort:   pagination.Newest(),
+				Limit:  11,
+				Offset: 20,
+			},
+		},
+		"after walks to older rows": {
+			page: pagination.Page{Size: 10, After: &cursor},
+			expectedFilter: repository.Filter{
+				Where: repository.All(where, repository.Any(
+					repository.Lt("created_at", cursor.CreatedAt),
+					repository.All(repository.Eq("created_at", cursor.CreatedAt), repository.Lt("id", cursor.ID)),
+				)),
+				Sort:  pagination.Newest(),
+				Limit: 11,
+			},
+		},
+		"before walks to newer rows from the cursor outwards": {
+			page: pagination.Page{Size: 10, Before: &cursor},
+			expectedFilter: repository.Filter{
+				Where: repository.All(where, repository.Any(
+					repository.Gt("created_at", cursor.CreatedAt),
+					repository.All(repository.Eq("created_at", cursor.CreatedAt), repository.Gt("id", cursor.ID)),
+				)),
+				Sort:  pagination.Oldest(),
+				Limit: 11,
+			},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expectedFilter, test.page.Filter(where))
+		})
+	}
+}
+
+func TestRows(t *testing.T) {
+	rows := tiedRows()
+	byID := func(ids ...uint) []row {
+		result := make([]row, 0, len(ids))
+		for _, id := range ids {
+			result = append(result, rows[id-1])
+		}
+		return result
+	}
+	cursor := rowCursor(rows[3])
+
+	testCases := map[string]struct {
+		page         pagination.Page
+		rows         []row
+		expectedIDs  []uint
+		expectedNext uint
+		expectedPrev uint
+	}{
+		"first page with more rows": {
+			page:         pagination.Page{Number: 1, Size: 2},
+			rows:         byID(7, 5, 8),
+			expectedIDs:  []uint{7, 5},
+			expectedNext: 5,
+		},
+		"only page": {
+			page:        pagination.Page{Number: 1, Size: 2},
+			rows:        byID(7, 5),
+			expectedIDs: []uint{7, 5},
+		},
+		"later page number": {
+			page:         pagination.Page{Number: 2, Size: 2},
+			rows:         byID(8, 4, 3),
+			expectedIDs:  []uint{8, 4},
+			expectedNext: 4,
+			expectedPrev: 8,
+		},
+		"after with more rows": {
+			page:         pagination.Page{Size: 2, After: &cursor},
+			rows:         byID(3, 1, 6),
+			expectedIDs:  []uint{3, 1},
+			expectedNext: 1,
+			expectedPrev: 3,
+		},
+		"last page after": {
+			page:         pagination.Page{Size: 2, After: &cursor},
+			rows:         byID(6, 2),
+			expectedIDs:  []uint{6, 2},
+			expectedPrev: 6,
+		},
+		"before is reversed into newest order": {
+			page:         pagination.Page{Size: 2, Before: &cursor},
+			rows:         byID(8, 5, 7),
+			expectedIDs:  []uint{5, 8},
+			expectedNext: 8,
+			expectedPrev: 5,
+		},
+		"first page before": {
+			page:         pagination.Page{Size: 2, Before: &cursor},
+			rows:         byID(8, 5),
+			expectedIDs:  []uint{5, 8},
+			expectedNext: 8,
+		},
+		"empty page": {
+			page:        pagination.Page{Size: 2, After: &cursor},
+			rows:        nil,
+			expectedIDs: []uint{},
+		},
+		"sorted page has no cursors": {
+			page:        pagination.Page{Number: 2, Size: 2, Sort: []repository.Sort{{Field: "amount"}}},
+			rows:        byID(1, 2, 3),
+			expectedIDs: []uint{1, 2},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			page, next, prev := pagination.Rows(test.page, test.rows, rowCursor)
+
+			assert.Equal(t, len(test.expectedIDs), len(page))
+			assert.Equal(t, test.expectedIDs, ids(page))
+			assert.Equal(t, cursorOfID(rows, test.expectedNext), next)
+			assert.Equal(t, cursorOfID(rows, test.expectedPrev), prev)
+		})
+	}
+}
+
+func cursorOfID(rows []row, id uint) string {
+	if id == 0 {
+		return ""
+	}
+
+	return rowCursor(rows[id-1]).Encode()
+}
+
+func TestWalkPages(t *testing.T) {
+	rows := tiedRows()
+
+	for size := 1; size <= len(rows)+1; size++ {
+		// forwards through next cursors from the first page
+		var pages [][]uint
+		page, err := pagination.NewPage(1, size, "", "")
+		assert.Equal(t, nil, err)
+		for {
+			found, next, _ := pagination.Rows(page, find(rows, page.Filter(nil)), rowCursor)
+			pages = append(pages, ids(found))
+			if next == "" {
+				break
+			}
+
+			page, err = pagination.NewPage(0, size, next, "")
+			assert.Equal(t, nil, err)
+		}
+		assert.Equal(t, newest, slices.Concat(pages...))
+
+		// backwards through prev cursors from the last page, every page keeps the newest order
+		var back [][]uint
+		for {
+			found, _, prev := pagination.Rows(page, find(rows, page.Filter(nil)), rowCursor)
+			back = append([][]uint{ids(found)}, back...)
+			if prev == "" {
+				break
+			}
+
+			page, err = pagination.NewPage(0, size, "", prev)
+			assert.Equal(t, nil, err)
+		}
+		assert.Equal(t, newest, slices.Concat(back...))
+	}
+}
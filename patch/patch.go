@@ -0,0 +1,399 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of RFC 7396 merge patches
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of RFC 6902 JSON patches
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrConflict     = errors.New("patch cannot be applied")
+)
+
+// Operation is one step of a JSON patch, From is only used by move and copy
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Merge applies an RFC 7396 merge patch to document, null members of the patch remove members of the document
+func Merge(document []byte, patch []byte) ([]byte, error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target any, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = merge(object[key], value)
+	}
+
+	return object
+}
+
+// Apply applies the operations of an RFC 6902 JSON patch to document in order, the document is left untouched
+// when any of them fails
+func Apply(document []byte, patch []byte) ([]byte, error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: a json patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	for i, operation := range operations {
+		target, err = apply(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(document any, operation Operation) (any, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("%w: %s has no path", ErrInvalidPatch, operation.Op)
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: %s has no value", ErrInvalidPatch, operation.Op)
+		}
+		value, err := decode(operation.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(document, path, value)
+		case "replace":
+			return replace(document, path, value)
+		}
+
+		current, err := get(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: %s does not hold the tested value", ErrConflict, *operation.Path)
+		}
+
+		return document, nil
+	case "remove":
+		return remove(document, path)
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, fmt.Errorf("%w: %s has no from", ErrInvalidPatch, operation.Op)
+		}
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			return add(document, path, clone(value))
+		}
+
+		// a value cannot be moved into one of its own members
+		if len(path) > len(from) && isPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", ErrConflict, *operation.From)
+		}
+		document, err = remove(document, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return add(document, path, value)
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, operation.Op)
+}
+
+func add(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(document, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+
+		return nil, fmt.Errorf("%w: /%s has no parent", ErrConflict, token)
+	})
+}
+
+func replace(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(document, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+
+		return setChild(parent, token, value), nil
+	})
+}
+
+func remove(document any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole document cannot be removed", ErrConflict)
+	}
+
+	return update(document, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+
+		switch node := parent.(type) {
+		case map[string]any:
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, _ := strconv.Atoi(token)
+			return append(node[:i], node[i+1:]...), nil
+		}
+
+		return parent, nil
+	})
+}
+
+// update walks down to the parent of the last token of path and replaces it with the result of fn
+func update(node any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	next, err := child(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := update(next, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return setChild(node, path[0], updated), nil
+}
+
+func get(document any, path []string) (any, error) {
+	node := document
+	for _, token := range path {
+		var err error
+		if node, err = child(node, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+func child(node any, token string) (any, error) {
+	switch node := node.(type) {
+	case map[string]any:
+		value, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrConflict, token)
+		}
+		return value, nil
+	case []any:
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		return node[i], nil
+	}
+
+	return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrConflict, token)
+}
+
+// setChild assumes token was already resolved by child
+func setChild(node any, token string, value any) any {
+	switch node := node.(type) {
+	case map[string]any:
+		node[token] = value
+	case []any:
+		i, _ := strconv.Atoi(token)
+		node[i] = value
+	}
+
+	return node
+}
+
+// index resolves an RFC 6901 array index, plain digits without a leading zero, strconv alone would take signs
+func index(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || strings.Trim(token, "0123456789") != "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPatch, token)
+	}
+	if i > last {
+		return 0, fmt.Errorf("%w: index %d is out of bounds", ErrConflict, i)
+	}
+
+	return i, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equal compares two decoded values the way RFC 6902 tests them, numbers by their numeric value
+func equal(a any, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, xOk := new(big.Rat).SetString(a.String())
+		y, yOk := new(big.Rat).SetString(b.String())
+		return xOk && yOk && x.Cmp(y) == 0
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+func clone(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		object := make(map[string]any, len(value))
+		for key, member := range value {
+			object[key] = clone(member)
+		}
+		return object
+	case []any:
+		array := make([]any, len(value))
+		for i, item := range value {
+			array[i] = clone(item)
+		}
+		return array
+	}
+
+	return value
+}
+
+// decode keeps numbers as json.Number so amounts survive a patch without going through a float
+func decode(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the json value")
+	}
+
+	return value, nil
+}
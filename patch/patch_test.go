@@ -0,0 +1,430 @@
+package patch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/patch"
+	"testing"
+)
+
+// canonical re-encodes a json document so documents can be compared whatever their member order and spacing
+func canonical(t *testing.T, document string) string {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(document)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("decoding %s: %v", document, err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(encoded)
+}
+
+// RFC 7396 Appendix A
+func TestMerge(t *testing.T) {
+	testCases := map[string]struct {
+		document string
+		patch    string
+		expected string
+	}{
+		"replaces a member":                       {document: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		"adds a member":                           {document: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		"removes a member":                        {document: `{"a":"b"}`, patch: `{"a":null}`, expected: `{}`},
+		"removes only the nulled member":          {document: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		"replaces an array with a string":         {document: `{"a":["b"]}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		"replaces a string with an array":         {document: `{"a":"c"}`, patch: `{"a":["b"]}`, expected: `{"a":["b"]}`},
+		"merges nested objects":                   {document: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, expected: `{"a":{"b":"d"}}`},
+		"replaces arrays as a whole":              {document: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, expected: `{"a":[1]}`},
+		"replaces an array document":              {document: `["a","b"]`, patch: `["c","d"]`, expected: `["c","d"]`},
+		"replaces an object with an array":        {document: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+		"replaces the document with null":         {document: `{"a":"foo"}`, patch: `null`, expected: `null`},
+		"replaces the document with a string":     {document: `{"a":"foo"}`, patch: `"bar"`, expected: `"bar"`},
+		"keeps nulls of the document":             {document: `{"e":null}`, patch: `{"a":1}`, expected: `{"e":null,"a":1}`},
+		"replaces an array with an object":        {document: `[1,2]`, patch: `{"a":"b","c":null}`, expected: `{"a":"b"}`},
+		"drops nulls of new nested objects":       {document: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, expected: `{"a":{"bb":{}}}`},
+		"keeps the precision of numbers":          {document: `{"amount":"1"}`, patch: `{"amount":12345678901234567890.01}`, expected: `{"amount":12345678901234567890.01}`},
+		"keeps members the patch does not name":   {document: `{"a":{"b":1,"c":2}}`, patch: `{"a":{"c":3}}`, expected: `{"a":{"b":1,"c":3}}`},
+		"replaces a scalar member with an object": {document: `{"a":1}`, patch: `{"a":{"b":null,"c":2}}`, expected: `{"a":{"c":2}}`},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			merged, err := patch.Merge([]byte(test.document), []byte(test.patch))
+
+			assert.Equal(t, nil, err)
+			assert.Equal(t, canonical(t, test.expected), string(merged))
+		})
+	}
+}
+
+func TestMergeInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		document    string
+		patch       string
+		expectedErr error
+	}{
+		"error patch is not json": {
+			document:    `{}`,
+			patch:       `{"a":`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error patch has trailing data": {
+			document:    `{}`,
+			patch:       `{"a":1} {"b":2}`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			merged, err := patch.Merge([]byte(test.document), []byte(test.patch))
+
+			assert.Equal(t, true, merged == nil)
+			assert.Equal(t, true, errors.Is(err, test.expectedErr))
+		})
+	}
+
+	t.Run("error document is not json", func(t *testing.T) {
+		merged, err := patch.Merge([]byte(`{`), []byte(`{}`))
+
+		assert.Equal(t, true, merged == nil)
+		assert.Equal(t, true, err != nil)
+		assert.Equal(t, false, errors.Is(err, patch.ErrInvalidPatch))
+	})
+}
+
+// RFC 6902 Appendix A, along with the edge cases of array indexes, pointers, move and test
+func TestApply(t *testing.T) {
+	testCases := map[string]struct {
+		document string
+		patch    string
+		expected string
+	}{
+		"A.1 adds an object member": {
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			expected: `{"baz":"qux","foo":"bar"}`,
+		},
+		"A.2 adds an array element": {
+			document: `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		"A.3 removes an object member": {
+			document: `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"remove","path":"/baz"}]`,
+			expected: `{"foo":"bar"}`,
+		},
+		"A.4 removes an array element": {
+			document: `{"foo":["bar","qux","baz"]}`,
+			patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			expected: `{"foo":["bar","baz"]}`,
+		},
+		"A.5 replaces a value": {
+			document: `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			expected: `{"baz":"boo","foo":"bar"}`,
+		},
+		"A.6 moves a value": {
+			document: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		"A.7 moves an array element": {
+			document: `{"foo":["all","grass","cows","eat"]}`,
+			patch:    `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			expected: `{"foo":["all","cows","eat","grass"]}`,
+		},
+		"A.8 tests a value": {
+			document: `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:    `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			expected: `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		"A.10 adds a nested member object": {
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			expected: `{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		"A.11 ignores unrecognized members of an operation": {
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			expected: `{"foo":"bar","baz":"qux"}`,
+		},
+		"A.14 unescapes ~1 before ~0": {
+			document: `{"/":9,"~1":10}`,
+			patch:    `[{"op":"test","path":"/~01","value":10}]`,
+			expected: `{"/":9,"~1":10}`,
+		},
+		"A.16 adds an array value": {
+			document: `{"foo":["bar"]}`,
+			patch:    `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			expected: `{"foo":["bar",["abc","def"]]}`,
+		},
+		"adds at the start of an array": {
+			document: `{"foo":["a","b"]}`,
+			patch:    `[{"op":"add","path":"/foo/0","value":"x"}]`,
+			expected: `{"foo":["x","a","b"]}`,
+		},
+		"adds at the length of an array": {
+			document: `{"foo":["a","b"]}`,
+			patch:    `[{"op":"add","path":"/foo/2","value":"x"}]`,
+			expected: `{"foo":["a","b","x"]}`,
+		},
+		"appends with -": {
+			document: `{"foo":[]}`,
+			patch:    `[{"op":"add","path":"/foo/-","value":1},{"op":"add","path":"/foo/-","value":2}]`,
+			expected: `{"foo":[1,2]}`,
+		},
+		"removes the first and the last array element": {
+			document: `{"foo":["a","b","c"]}`,
+			patch:    `[{"op":"remove","path":"/foo/2"},{"op":"remove","path":"/foo/0"}]`,
+			expected: `{"foo":["b"]}`,
+		},
+		"removes the only array element": {
+			document: `["a"]`,
+			patch:    `[{"op":"remove","path":"/0"}]`,
+			expected: `[]`,
+		},
+		"adds a member named -": {
+			document: `{}`,
+			patch:    `[{"op":"add","path":"/-","value":1}]`,
+			expected: `{"-":1}`,
+		},
+		"adds a member with a slash and a tilde": {
+			document: `{}`,
+			patch:    `[{"op":"add","path":"/a~1b","value":1},{"op":"add","path":"/c~0d","value":2}]`,
+			expected: `{"a/b":1,"c~d":2}`,
+		},
+		"adds an empty member name": {
+			document: `{"":0}`,
+			patch:    `[{"op":"replace","path":"/","value":1}]`,
+			expected: `{"":1}`,
+		},
+		"replaces the whole document": {
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"","value":[1]}]`,
+			expected: `[1]`,
+		},
+		"moves a value onto itself": {
+			document: `{"a":{"b":1}}`,
+			patch:    `[{"op":"move","from":"/a","path":"/a"}]`,
+			expected: `{"a":{"b":1}}`,
+		},
+		"moves a value next to a member sharing its prefix": {
+			document: `{"a":1}`,
+			patch:    `[{"op":"move","from":"/a","path":"/ab"}]`,
+			expected: `{"ab":1}`,
+		},
+		"moves an array element backwards": {
+			document: `["a","b","c"]`,
+			patch:    `[{"op":"move","from":"/2","path":"/0"}]`,
+			expected: `["c","a","b"]`,
+		},
+		"copies a value without sharing it": {
+			document: `{"a":{"b":1}}`,
+			patch:    `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			expected: `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		"tests numbers by their value": {
+			document: `{"a":1,"b":[0.5],"c":{"d":100}}`,
+			patch: `[{"op":"test","path":"/a","value":1.0},{"op":"test","path":"/a","value":1e0},` +
+				`{"op":"test","path":"/b","value":[5e-1]},{"op":"test","path":"/c","value":{"d":1E2}}]`,
+			expected: `{"a":1,"b":[0.5],"c":{"d":100}}`,
+		},
+		"tests numbers beyond float precision": {
+			document: `{"amount":12345678901234567890.01}`,
+			patch:    `[{"op":"test","path":"/amount","value":1234567890123456789001e-2}]`,
+			expected: `{"amount":12345678901234567890.01}`,
+		},
+		"tests the whole document": {
+			document: `{"a":[1,{"b":null}]}`,
+			patch:    `[{"op":"test","path":"","value":{"a":[1,{"b":null}]}}]`,
+			expected: `{"a":[1,{"b":null}]}`,
+		},
+		"runs an empty patch": {
+			document: `{"a":1}`,
+			patch:    `[]`,
+			expected: `{"a":1}`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			patched, err := patch.Apply([]byte(test.document), []byte(test.patch))
+
+			assert.Equal(t, nil, err)
+			assert.Equal(t, canonical(t, test.expected), string(patched))
+		})
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		document    string
+		patch       string
+		expectedErr error
+	}{
+		"A.9 error tested value differs": {
+			document:    `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:       `[{"op":"test","path":"/baz","value":"bar"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"A.12 error adding to a nonexistent target": {
+			document:    `{"foo":"bar"}`,
+			patch:       `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"A.15 error comparing a string and a number": {
+			document:    `{"/":9,"~1":10}`,
+			patch:       `[{"op":"test","path":"/~01","value":"10"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error tested numbers differ": {
+			document:    `{"a":0.1}`,
+			patch:       `[{"op":"test","path":"/a","value":0.10000000000000001}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error tested array has another length": {
+			document:    `{"a":[1,2]}`,
+			patch:       `[{"op":"test","path":"/a","value":[1,2,3]}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error tested object has another member": {
+			document:    `{"a":{"b":1}}`,
+			patch:       `[{"op":"test","path":"/a","value":{"c":1}}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error adding past the length of an array": {
+			document:    `{"foo":["a","b"]}`,
+			patch:       `[{"op":"add","path":"/foo/3","value":"x"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error removing past the end of an array": {
+			document:    `{"foo":["a","b"]}`,
+			patch:       `[{"op":"remove","path":"/foo/2"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error removing from an empty array": {
+			document:    `[]`,
+			patch:       `[{"op":"remove","path":"/0"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error removing - of an array": {
+			document:    `["a"]`,
+			patch:       `[{"op":"remove","path":"/-"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error replacing - of an array": {
+			document:    `["a"]`,
+			patch:       `[{"op":"replace","path":"/-","value":"b"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error index with a leading zero": {
+			document:    `{"foo":["a","b"]}`,
+			patch:       `[{"op":"add","path":"/foo/01","value":"x"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error index with a leading zero while walking": {
+			document:    `[{"a":1},{"a":2}]`,
+			patch:       `[{"op":"replace","path":"/00/a","value":3}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error negative index": {
+			document:    `["a","b"]`,
+			patch:       `[{"op":"remove","path":"/-1"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error index with a sign": {
+			document:    `["a","b"]`,
+			patch:       `[{"op":"remove","path":"/+1"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error empty index": {
+			document:    `["a","b"]`,
+			patch:       `[{"op":"add","path":"/","value":"x"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error index that is not a number": {
+			document:    `["a","b"]`,
+			patch:       `[{"op":"replace","path":"/one","value":"x"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error moving a value into itself": {
+			document:    `{"a":{"b":{}}}`,
+			patch:       `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error moving a missing value": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"move","from":"/b","path":"/c"}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error replacing a missing member": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"replace","path":"/b","value":2}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error removing the whole document": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"remove","path":""}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error walking into a scalar": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"add","path":"/a/b","value":2}]`,
+			expectedErr: patch.ErrConflict,
+		},
+		"error path without a leading slash": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"remove","path":"a"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error missing path": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"remove"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error missing value": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"add","path":"/b"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error missing from": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"copy","path":"/b"}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error unknown op": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"increment","path":"/a","value":1}]`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error patch is not an array": {
+			document:    `{"a":1}`,
+			patch:       `{"op":"remove","path":"/a"}`,
+			expectedErr: patch.ErrInvalidPatch,
+		},
+		"error later operation fails": {
+			document:    `{"a":1}`,
+			patch:       `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`,
+			expectedErr: patch.ErrConflict,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			patched, err := patch.Apply([]byte(test.document), []byte(test.patch))
+
+			assert.Equal(t, true, patched == nil)
+			assert.Equal(t, true, errors.Is(err, test.expectedErr))
+		})
+	}
+}
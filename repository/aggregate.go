@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"fmt"
+	"gorm.io/gorm/clause"
+	"regexp"
+	"strings"
+)
+
+// amountPattern is a column, or a sum or difference of columns such as "amount - refunded_amount"
+var amountPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*( [-+] [a-z_][a-z0-9_]*)*$`)
+
+// Aggregate is what Stats and Series compute over the rows matching a filter. Amount is counted, summed and
+// averaged, Time is the timestamp column whose first and last value Stats reports, Series buckets Bucket.Field
+// instead. GroupBy splits the rows into one result per distinct value of its columns
+type Aggregate struct {
+	Amount  string
+	Time    string
+	GroupBy []string
+}
+
+// amount builds Amount with every column quoted
+func (a Aggregate) amount() (clause.Expression, error) {
+	if !amountPattern.MatchString(a.Amount) {
+		return nil, fmt.Errorf("%w: cannot aggregate %q", ErrInvalidFilter, a.Amount)
+	}
+
+	tokens := strings.Split(a.Amount, " ")
+	sql := make([]string, 0, len(tokens))
+	vars := make([]interface{}, 0, len(tokens)/2+1)
+	for i, token := range tokens {
+		if i%2 == 1 {
+			sql = append(sql, token)
+			continue
+		}
+		sql = append(sql, "?")
+		vars = append(vars, clause.Column{Name: token})
+	}
+
+	return clause.Expr{SQL: "(" + strings.Join(sql, " ") + ")", Vars: vars}, nil
+}
+
+func (a Aggregate) groupBy() ([]clause.Column, error) {
+	columns := make([]clause.Column, 0, len(a.GroupBy))
+	for _, field := range a.GroupBy {
+		if !fieldPattern.MatchString(field) {
+			return nil, fmt.Errorf("%w: cannot group by %q", ErrInvalidFilter, field)
+		}
+		columns = append(columns, clause.Column{Name: field})
+	}
+
+	return columns, nil
+}
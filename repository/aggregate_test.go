@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/model"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// aggregateSQL renders the query Stats or Series runs on the transactions table, scanning is not supported on a
+// dry run so only errors found before the query runs are returned
+func aggregateSQL(t *testing.T, run func(repo DatabaseRepository[model.Transaction]) error) (string, error) {
+	t.Helper()
+
+	db := dryRun(t)
+	var sql string
+	_ = db.Callback().Row().After("gorm:row").Register("test:capture", func(db *gorm.DB) {
+		sql = db.Statement.SQL.String()
+	})
+
+	err := run(&DatabaseRepositoryImpl[model.Transaction]{db: db})
+	if errors.Is(err, ErrInvalidFilter) {
+		return "", err
+	}
+
+	return sql, nil
+}
+
+func TestStatsSQL(t *testing.T) {
+	testCases := map[string]struct {
+		aggregate   Aggregate
+		expectedSQL string
+	}{
+		"net amount per currency": {
+			aggregate: Aggregate{Amount: model.TransactionNetAmount, Time: "created_at", GroupBy: []string{"currency"}},
+			expectedSQL: `SELECT "currency", COUNT(*) AS count, ` +
+				`CAST(COALESCE(SUM(("amount" - "refunded_amount")), 0) AS BIGINT) AS sum, ` +
+				`CAST(COALESCE(ROUND(AVG(("amount" - "refunded_amount"))), 0) AS BIGINT) AS average, ` +
+				`COALESCE(MIN(("amount" - "refunded_amount")), 0) AS min, ` +
+				`COALESCE(MAX(("amount" - "refunded_amount")), 0) AS max, ` +
+				`MIN("created_at") AS first_transaction_at, MAX("created_at") AS last_transaction_at ` +
+				`FROM "transactions" WHERE "transactions"."is_deleted" = $1 AND "user_id" = $2 GROUP BY "currency"`,
+		},
+		"single column over every row": {
+			aggregate: Aggregate{Amount: "amount", Time: "updated_at"},
+			expectedSQL: `SELECT COUNT(*) AS count, ` +
+				`CAST(COALESCE(SUM(("amount")), 0) AS BIGINT) AS sum, ` +
+				`CAST(COALESCE(ROUND(AVG(("amount"))), 0) AS BIGINT) AS average, ` +
+				`COALESCE(MIN(("amount")), 0) AS min, ` +
+				`COALESCE(MAX(("amount")), 0) AS max, ` +
+				`MIN("updated_at") AS first_transaction_at, MAX("updated_at") AS last_transaction_at ` +
+				`FROM "transactions" WHERE "transactions"."is_deleted" = $1 AND "user_id" = $2`,
+		},
+		"sum of three columns": {
+			aggregate: Aggregate{Amount: "amount + fee - refunded_amount", Time: "created_at", GroupBy: []string{"user_id", "currency"}},
+			expectedSQL: `SELECT "user_id", "currency", COUNT(*) AS count, ` +
+				`CAST(COALESCE(SUM(("amount" + "fee" - "refunded_amount")), 0) AS BIGINT) AS sum, ` +
+				`CAST(COALESCE(ROUND(AVG(("amount" + "fee" - "refunded_amount"))), 0) AS BIGINT) AS average, ` +
+				`COALESCE(MIN(("amount" + "fee" - "refunded_amount")), 0) AS min, ` +
+				`COALESCE(MAX(("amount" + "fee" - "refunded_amount")), 0) AS max, ` +
+				`MIN("created_at") AS first_transaction_at, MAX("created_at") AS last_transaction_at ` +
+				`FROM "transactions" WHERE "transactions"."is_deleted" = $1 AND "user_id" = $2 GROUP BY "user_id","currency"`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sql, err := aggregateSQL(t, func(repo DatabaseRepository[model.Transaction]) error {
+				_, err := repo.Stats(context.Background(), Filter{Where: Eq("user_id", uint(1))}, test.aggregate)
+				return err
+			})
+
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.expectedSQL, sql)
+		})
+	}
+}
+
+func TestSeriesSQL(t *testing.T) {
+	sql, err := aggregateSQL(t, func(repo DatabaseRepository[model.Transaction]) error {
+		_, err := repo.Series(context.Background(), Filter{}, Bucket{Field: "created_at", Interval: IntervalDay}, Aggregate{
+			Amount:  model.TransactionNetAmount,
+			GroupBy: []string{"status", "currency"},
+		})
+		return err
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `SELECT date_trunc($1, "created_at" AT TIME ZONE $2) AT TIME ZONE $3 AS bucket, "status", "currency", `+
+		`COUNT(*) AS count, CAST(COALESCE(SUM(("amount" - "refunded_amount")), 0) AS BIGINT) AS sum `+
+		`FROM "transactions" WHERE "transactions"."is_deleted" = $4 GROUP BY "bucket","status","currency" ORDER BY "bucket"`, sql)
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	bucket := Bucket{Field: "created_at", Interval: IntervalDay}
+
+	testCases := map[string]struct {
+		aggregate Aggregate
+		stats     bool
+	}{
+		"error empty amount":                      {aggregate: Aggregate{Time: "created_at"}, stats: true},
+		"error amount with sql":                   {aggregate: Aggregate{Amount: "amount); DROP TABLE users; --", Time: "created_at"}, stats: true},
+		"error amount with a function":            {aggregate: Aggregate{Amount: "abs(amount)", Time: "created_at"}},
+		"error amount with a multiplication":      {aggregate: Aggregate{Amount: "amount * 2", Time: "created_at"}},
+		"error amount without spaces":             {aggregate: Aggregate{Amount: "amount-refunded_amount", Time: "created_at"}},
+		"error amount ending with an operator":    {aggregate: Aggregate{Amount: "amount -", Time: "created_at"}, stats: true},
+		"error amount with a quoted column":       {aggregate: Aggregate{Amount: `"amount"`, Time: "created_at"}},
+		"error time with sql":                     {aggregate: Aggregate{Amount: "amount", Time: "created_at; --"}, stats: true},
+		"error missing time":                      {aggregate: Aggregate{Amount: "amount"}, stats: true},
+		"error group by an expression":            {aggregate: Aggregate{Amount: "amount", Time: "created_at", GroupBy: []string{"lower(currency)"}}},
+		"error group by an expression in stats":   {aggregate: Aggregate{Amount: "amount", Time: "created_at", GroupBy: []string{"currency", "1=1"}}, stats: true},
+		"error amount with an uppercase column":   {aggregate: Aggregate{Amount: "Amount", Time: "created_at"}, stats: true},
+		"error amount with two spaces":            {aggregate: Aggregate{Amount: "amount  - refunded_amount", Time: "created_at"}},
+		"error amount with a trailing whitespace": {aggregate: Aggregate{Amount: "amount ", Time: "created_at"}, stats: true},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := aggregateSQL(t, func(repo DatabaseRepository[model.Transaction]) error {
+				if test.stats {
+					_, err := repo.Stats(context.Background(), Filter{}, test.aggregate)
+					return err
+				}
+				_, err := repo.Series(context.Background(), Filter{}, bucket, test.aggregate)
+				return err
+			})
+
+			assert.Equal(t, true, errors.Is(err, ErrInvalidFilter))
+		})
+	}
+}
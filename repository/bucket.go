@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"fmt"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Interval string
+
+const (
+	IntervalHour  Interval = "hour"
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// Bucket truncates a timestamp column to the start of its interval on the wall clock of Location,
+// weeks start on monday
+type Bucket struct {
+	Field    string
+	Interval Interval
+	Location *time.Location
+}
+
+func (i Interval) valid() bool {
+	switch i {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	}
+
+	return false
+}
+
+func (b Bucket) build() (clause.Expression, error) {
+	if !fieldPattern.MatchString(b.Field) {
+		return nil, fmt.Errorf("%w: cannot bucket %q", ErrInvalidFilter, b.Field)
+	}
+	if !b.Interval.valid() {
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrInvalidFilter, b.Interval)
+	}
+
+	location := b.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	// truncate the local time, then turn it back into an instant of the same zone
+	return clause.Expr{
+		SQL:  "date_trunc(?, ? AT TIME ZONE ?) AT TIME ZONE ?",
+		Vars: []interface{}{string(b.Interval), clause.Column{Name: b.Field}, location.String(), location.String()},
+	}, nil
+}
@@ -1,17 +1,28 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 type DatabaseRepository[T any] interface {
-	First(conds ...interface{}) (*T, error)
-	Create(value *T) (*T, error)
-	Find(filter string) ([]T, error)
-	Save(value interface{}, conds ...interface{}) (*T, error)
-	AverageTransaction() ([]dto.AverageTransactionAttr, error)
+	First(ctx context.Context, id interface{}) (*T, error)
+	Create(ctx context.Context, value *T) (*T, error)
+	CreateInBatches(ctx context.Context, values []T, batchSize int) ([]T, error)
+	Find(ctx context.Context, filter Filter) ([]T, error)
+	Each(ctx context.Context, filter Filter, fn func(value *T) error) error
+	Count(ctx context.Context, filter Filter) (int64, error)
+	Save(ctx context.Context, value *T, id interface{}) (*T, error)
+	UpdateAll(ctx context.Context, filter Filter, values map[string]interface{}) ([]T, error)
+	Stats(ctx context.Context, filter Filter, aggregate Aggregate) ([]dto.TransactionStatsAttr, error)
+	Series(ctx context.Context, filter Filter, bucket Bucket, aggregate Aggregate) ([]dto.TransactionSeriesAttr, error)
+	Purge(ctx context.Context, deletedBefore time.Time, filter Filter) (int64, error)
 }
 
 type DatabaseRepositoryImpl[T any] struct {
@@ -24,62 +35,355 @@ func NewDatabaseRepository[T any](db *gorm.DB) DatabaseRepository[T] {
 	}
 }
 
-func (r *DatabaseRepositoryImpl[T]) First(conds ...interface{}) (*T, error) {
+func (r *DatabaseRepositoryImpl[T]) First(ctx context.Context, id interface{}) (*T, error) {
 	var entity T
-	query := r.db.Where("id = ? AND is_deleted = ?", conds, false)
+	query := locked(ctx, r.scoped(ctx)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id})
 
 	if err := query.First(&entity).Error; err != nil {
-		return nil, err
+		return nil, contextError(ctx, err)
 	}
 
 	return &entity, nil
 }
 
-func (r *DatabaseRepositoryImpl[T]) Create(value *T) (*T, error) {
-	if err := r.db.Create(value).Error; err != nil {
-		return nil, err
+func (r *DatabaseRepositoryImpl[T]) Create(ctx context.Context, value *T) (*T, error) {
+	if err := conn(ctx, r.db).Create(value).Error; err != nil {
+		return nil, contextError(ctx, err)
 	}
 
 	return value, nil
 }
 
-func (r *DatabaseRepositoryImpl[T]) Find(filter string) ([]T, error) {
-	var entity []T
-	query := "SELECT * FROM transactions WHERE is_deleted = false"
+// CreateInBatches inserts values with one statement per batchSize rows, wrap it in a Transactor to insert
+// all of them or none
+func (r *DatabaseRepositoryImpl[T]) CreateInBatches(ctx context.Context, values []T, batchSize int) ([]T, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
 
-	if filter != "" {
-		query = fmt.Sprintf("%s %s", query, filter)
+	if err := conn(ctx, r.db).CreateInBatches(&values, batchSize).Error; err != nil {
+		return nil, contextError(ctx, err)
 	}
 
-	if err := r.db.Raw(query).Scan(&entity).Error; err != nil {
+	return values, nil
+}
+
+func (r *DatabaseRepositoryImpl[T]) Find(ctx context.Context, filter Filter) ([]T, error) {
+	var entity []T
+	query, err := applyFilter(locked(ctx, r.scoped(ctx)), filter)
+	if err != nil {
 		return nil, err
 	}
 
-	r.db.Save(entity)
+	if err := query.Find(&entity).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
 
 	return entity, nil
 }
 
-func (r *DatabaseRepositoryImpl[T]) Save(value interface{}, conds ...interface{}) (*T, error) {
+// Each calls fn with every row matching the filter in order, rows are read from a cursor one at a time instead
+// of being loaded together. It stops at the first error returned by fn
+func (r *DatabaseRepositoryImpl[T]) Each(ctx context.Context, filter Filter, fn func(value *T) error) error {
+	query, err := applyFilter(r.scoped(ctx), filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entity T
+		if err := query.ScanRows(rows, &entity); err != nil {
+			return contextError(ctx, err)
+		}
+		if err := fn(&entity); err != nil {
+			return err
+		}
+	}
+
+	return contextError(ctx, rows.Err())
+}
+
+// Count returns the number of rows matching the filter, its sort, limit and offset are ignored
+func (r *DatabaseRepositoryImpl[T]) Count(ctx context.Context, filter Filter) (int64, error) {
+	var total int64
+	query, err := applyFilter(r.scoped(ctx), Filter{Where: filter.Where})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return 0, contextError(ctx, err)
+	}
+
+	return total, nil
+}
+
+// Save writes every field of value, when T opts into model.Version the row is only updated if it still has
+// the version of value, otherwise a *ConflictError is returned
+func (r *DatabaseRepositoryImpl[T]) Save(ctx context.Context, value *T, id interface{}) (*T, error) {
 	var entity T
-	if err := r.db.Save(value).Error; err != nil {
+	db := conn(ctx, r.db)
+	if versioned, ok := any(value).(model.Versioned); ok {
+		if err := saveVersioned(db, value, versioned, id); err != nil {
+			return nil, contextError(ctx, err)
+		}
+	} else if err := db.Save(value).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	// read back regardless of soft delete, the saved value may just have been deleted
+	if err := db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(&entity).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return &entity, nil
+}
+
+// saveVersioned updates the row of value and bumps its version, provided nobody else saved it in between
+func saveVersioned[T any](db *gorm.DB, value *T, versioned model.Versioned, id interface{}) error {
+	expected := versioned.CurrentVersion()
+	versioned.SetVersion(expected + 1)
+
+	column := clause.Column{Table: clause.CurrentTable, Name: versioned.VersionColumn()}
+	result := db.Model(value).
+		Select("*").
+		Omit(clause.Associations).
+		Where(clause.Eq{Column: column, Value: expected}).
+		Updates(value)
+	if result.Error != nil {
+		versioned.SetVersion(expected)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// nothing was updated, either the row is gone or its version moved on
+	versioned.SetVersion(expected)
+	var count int64
+	if err := db.Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return &ConflictError{Version: expected}
+}
+
+// UpdateAll sets values on every row matching the filter in a single statement and returns the updated rows,
+// its sort, limit and offset are ignored. The version of models opting into model.Version is bumped
+func (r *DatabaseRepositoryImpl[T]) UpdateAll(ctx context.Context, filter Filter, values map[string]interface{}) ([]T, error) {
+	var entity []T
+	var where clause.Expression
+	if filter.Where != nil {
+		var err error
+		if where, err = filter.Where.build(); err != nil {
+			return nil, err
+		}
+	}
+	if where == nil {
+		return nil, fmt.Errorf("%w: updating every row needs a where", ErrInvalidFilter)
+	}
+	query := r.scoped(ctx).Where(where)
+
+	assignments := make(map[string]interface{}, len(values)+1)
+	for column, value := range values {
+		assignments[column] = value
+	}
+	var row T
+	if versioned, ok := any(&row).(model.Versioned); ok {
+		column := versioned.VersionColumn()
+		assignments[column] = gorm.Expr("? + 1", clause.Column{Name: column})
+	}
+
+	if err := query.Model(&entity).Clauses(clause.Returning{}).Updates(assignments).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return entity, nil
+}
+
+// Stats aggregates the amount and time of rows matching the filter, one result per distinct value of the
+// groupBy columns, or a single result over every row when there are none. Amounts are only meaningful when
+// grouping by currency, the average is rounded to the minor unit
+func (r *DatabaseRepositoryImpl[T]) Stats(ctx context.Context, filter Filter, aggregate Aggregate) ([]dto.TransactionStatsAttr, error) {
+	var entity []dto.TransactionStatsAttr
+	query, err := applyFilter(r.scoped(ctx), Filter{Where: filter.Where})
+	if err != nil {
 		return nil, err
 	}
 
-	if err := r.db.First(&entity, conds...).Error; err != nil {
+	amount, err := aggregate.amount()
+	if err != nil {
+		return nil, err
+	}
+	if !fieldPattern.MatchString(aggregate.Time) {
+		return nil, fmt.Errorf("%w: cannot aggregate %q", ErrInvalidFilter, aggregate.Time)
+	}
+	columns, err := aggregate.groupBy()
+	if err != nil {
 		return nil, err
 	}
 
-	return &entity, nil
+	selects := make([]string, 0, len(columns)+7)
+	vars := make([]interface{}, 0, len(columns)+6)
+	for _, column := range columns {
+		selects = append(selects, "?")
+		vars = append(vars, column)
+	}
+	selects = append(selects,
+		"COUNT(*) AS count",
+		"CAST(COALESCE(SUM(?), 0) AS BIGINT) AS sum",
+		"CAST(COALESCE(ROUND(AVG(?)), 0) AS BIGINT) AS average",
+		"COALESCE(MIN(?), 0) AS min",
+		"COALESCE(MAX(?), 0) AS max",
+		"MIN(?) AS first_transaction_at",
+		"MAX(?) AS last_transaction_at",
+	)
+	at := clause.Column{Name: aggregate.Time}
+	vars = append(vars, amount, amount, amount, amount, at, at)
+
+	query = query.Select(strings.Join(selects, ", "), vars...)
+	if len(columns) > 0 {
+		query = query.Clauses(clause.GroupBy{Columns: columns})
+	}
+
+	if err := query.Scan(&entity).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return entity, nil
 }
 
-func (r *DatabaseRepositoryImpl[T]) AverageTransaction() ([]dto.AverageTransactionAttr, error) {
-	var entity []dto.AverageTransactionAttr
-	query := "SELECT user_id, AVG(amount) AS avg_transaction FROM transactions WHERE is_deleted = false GROUP BY transactions.user_id"
+// Series counts and sums the amount of rows matching the filter per bucket and distinct value of the groupBy
+// columns, ordered by bucket, buckets without rows are left out
+func (r *DatabaseRepositoryImpl[T]) Series(ctx context.Context, filter Filter, bucket Bucket, aggregate Aggregate) ([]dto.TransactionSeriesAttr, error) {
+	var entity []dto.TransactionSeriesAttr
+	query, err := applyFilter(r.scoped(ctx), Filter{Where: filter.Where})
+	if err != nil {
+		return nil, err
+	}
 
-	if err := r.db.Raw(query).Scan(&entity).Error; err != nil {
+	start, err := bucket.build()
+	if err != nil {
 		return nil, err
 	}
+	amount, err := aggregate.amount()
+	if err != nil {
+		return nil, err
+	}
+	groupBy, err := aggregate.groupBy()
+	if err != nil {
+		return nil, err
+	}
+
+	// grouping by the alias keeps postgres from comparing the bound parameters of two copies of start
+	columns := []clause.Column{{Name: "bucket"}}
+	selects := []string{"? AS bucket"}
+	vars := []interface{}{start}
+	for _, column := range groupBy {
+		columns = append(columns, column)
+		selects = append(selects, "?")
+		vars = append(vars, column)
+	}
+	selects = append(selects,
+		"COUNT(*) AS count",
+		"CAST(COALESCE(SUM(?), 0) AS BIGINT) AS sum",
+	)
+	vars = append(vars, amount)
+
+	query = query.Select(strings.Join(selects, ", "), vars...).
+		Clauses(clause.GroupBy{Columns: columns}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "bucket"}})
+
+	if err := query.Scan(&entity).Error; err != nil {
+		return nil, contextError(ctx, err)
+	}
 
 	return entity, nil
 }
+
+// Purge permanently removes the rows matching the filter that were soft deleted before deletedBefore and returns
+// how many were removed, T must opt into model.SoftDelete
+func (r *DatabaseRepositoryImpl[T]) Purge(ctx context.Context, deletedBefore time.Time, filter Filter) (int64, error) {
+	var entity T
+	softDeletable, ok := any(&entity).(model.SoftDeletable)
+	if !ok {
+		return 0, fmt.Errorf("%T is not soft deletable", entity)
+	}
+
+	query, err := applyFilter(conn(ctx, r.db), Filter{Where: filter.Where})
+	if err != nil {
+		return 0, err
+	}
+
+	result := query.
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: softDeletable.SoftDeleteColumn()}, Value: true}).
+		Where(clause.Lt{Column: clause.Column{Table: clause.CurrentTable, Name: softDeletable.DeletedAtColumn()}, Value: deletedBefore}).
+		Delete(&entity)
+	if result.Error != nil {
+		return 0, contextError(ctx, result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// scoped starts a query on the table of T, excluding soft deleted rows when T opts into model.SoftDelete, or
+// keeping only them when ctx comes from OnlyDeleted
+func (r *DatabaseRepositoryImpl[T]) scoped(ctx context.Context) *gorm.DB {
+	var entity T
+	query := conn(ctx, r.db).Model(&entity)
+
+	if softDeletable, ok := any(&entity).(model.SoftDeletable); ok {
+		column := clause.Column{Table: clause.CurrentTable, Name: softDeletable.SoftDeleteColumn()}
+		query = query.Where(clause.Eq{Column: column, Value: onlyDeleted(ctx)})
+	}
+
+	return query
+}
+
+// contextError reports why the context ended instead of the driver error, so callers can tell a
+// cancelled or timed out request apart from a failing database
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+func applyFilter(query *gorm.DB, filter Filter) (*gorm.DB, error) {
+	if filter.Where != nil {
+		where, err := filter.Where.build()
+		if err != nil {
+			return nil, err
+		}
+		if where != nil {
+			query = query.Where(where)
+		}
+	}
+
+	for _, s := range filter.Sort {
+		order, err := s.build()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Order(order)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	return query, nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestPurgeSQL(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sql string
+	var vars []interface{}
+	_ = db.Callback().Delete().After("gorm:delete").Register("test:capture", func(db *gorm.DB) {
+		sql, vars = db.Statement.SQL.String(), db.Statement.Vars
+	})
+
+	deletedBefore := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &DatabaseRepositoryImpl[model.Transaction]{db: db}
+	_, err = repo.Purge(context.Background(), deletedBefore, Filter{Where: NotIn("status", model.SettledStatuses())})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `DELETE FROM "transactions" WHERE "status" NOT IN ($1,$2,$3) `+
+		`AND "transactions"."is_deleted" = $4 AND "transactions"."deleted_at" < $5`, sql)
+	assert.Equal(t, []interface{}{"success", "partially_refunded", "refunded", true, deletedBefore}, vars)
+}
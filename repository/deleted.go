@@ -0,0 +1,18 @@
+package repository
+
+import "context"
+
+// deletedKey marks a context whose queries read soft deleted rows
+type deletedKey struct{}
+
+// OnlyDeleted makes repositories of soft deletable models called with the returned context read the soft
+// deleted rows instead of the live ones
+func OnlyDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedKey{}, true)
+}
+
+func onlyDeleted(ctx context.Context) bool {
+	deleted, _ := ctx.Value(deletedKey{}).(bool)
+
+	return deleted
+}
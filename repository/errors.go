@@ -0,0 +1,12 @@
+package repository
+
+import "fmt"
+
+// ConflictError reports a row that was saved by someone else since Version was read
+type ConflictError struct {
+	Version uint
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("row was changed since version %d was read", e.Version)
+}
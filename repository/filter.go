@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+var fieldPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// likeEscaper keeps wildcards typed by users from being interpreted by LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type Operator string
+
+const (
+	OpEqual              Operator = "eq"
+	OpNotEqual           Operator = "neq"
+	OpGreaterThan        Operator = "gt"
+	OpGreaterThanOrEqual Operator = "gte"
+	OpLessThan           Operator = "lt"
+	OpLessThanOrEqual    Operator = "lte"
+	OpIn                 Operator = "in"
+	OpNotIn              Operator = "not_in"
+	OpContains           Operator = "contains"
+	OpKeyEqual           Operator = "key_eq"
+	OpHas                Operator = "has"
+)
+
+type Match string
+
+const (
+	MatchAll Match = "all"
+	MatchAny Match = "any"
+)
+
+// Expression is implemented by Condition, Group and Subquery, it can only be turned into SQL by the repository
+type Expression interface {
+	build() (clause.Expression, error)
+}
+
+// Condition compares Field with Value, Key names the member of a jsonb object field that OpKeyEqual compares
+type Condition struct {
+	Field    string
+	Operator Operator
+	Key      string
+	Value    interface{}
+}
+
+type Group struct {
+	Match       Match
+	Expressions []Expression
+}
+
+// Subquery matches rows whose Field is among the Column values of the rows of Table matching Where, fields of
+// Where refer to Table
+type Subquery struct {
+	Field  string
+	Table  string
+	Column string
+	Where  Expression
+}
+
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// Filter describes which rows Find returns, every value is sent to the database as a bound parameter
+type Filter struct {
+	Where  Expression
+	Sort   []Sort
+	Limit  int
+	Offset int
+}
+
+func Eq(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpEqual, Value: value}
+}
+
+func Neq(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpNotEqual, Value: value}
+}
+
+func Gt(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpGreaterThan, Value: value}
+}
+
+func Gte(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpGreaterThanOrEqual, Value: value}
+}
+
+func Lt(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpLessThan, Value: value}
+}
+
+func Lte(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpLessThanOrEqual, Value: value}
+}
+
+func In(field string, values interface{}) Condition {
+	return Condition{Field: field, Operator: OpIn, Value: values}
+}
+
+func NotIn(field string, values interface{}) Condition {
+	return Condition{Field: field, Operator: OpNotIn, Value: values}
+}
+
+// Contains matches text fields containing value, ignoring case
+func Contains(field string, value string) Condition {
+	return Condition{Field: field, Operator: OpContains, Value: value}
+}
+
+// KeyEq matches jsonb object fields whose member key equals value once read as text, so a number 123 and a
+// string "123" both equal "123"
+func KeyEq(field string, key string, value string) Condition {
+	return Condition{Field: field, Operator: OpKeyEqual, Key: key, Value: value}
+}
+
+// Has matches jsonb array fields holding value
+func Has(field string, value interface{}) Condition {
+	return Condition{Field: field, Operator: OpHas, Value: value}
+}
+
+// InQuery matches rows whose field is the column of a row of table matching where, such as the transactions
+// having a journal in the ledger
+func InQuery(field string, table string, column string, where Expression) Subquery {
+	return Subquery{Field: field, Table: table, Column: column, Where: where}
+}
+
+func All(expressions ...Expression) Group {
+	return Group{Match: MatchAll, Expressions: expressions}
+}
+
+func Any(expressions ...Expression) Group {
+	return Group{Match: MatchAny, Expressions: expressions}
+}
+
+func (c Condition) build() (clause.Expression, error) {
+	if !fieldPattern.MatchString(c.Field) {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, c.Field)
+	}
+
+	column := clause.Column{Name: c.Field}
+	switch c.Operator {
+	case OpEqual:
+		return clause.Eq{Column: column, Value: c.Value}, nil
+	case OpNotEqual:
+		return clause.Neq{Column: column, Value: c.Value}, nil
+	case OpGreaterThan:
+		return clause.Gt{Column: column, Value: c.Value}, nil
+	case OpGreaterThanOrEqual:
+		return clause.Gte{Column: column, Value: c.Value}, nil
+	case OpLessThan:
+		return clause.Lt{Column: column, Value: c.Value}, nil
+	case OpLessThanOrEqual:
+		return clause.Lte{Column: column, Value: c.Value}, nil
+	case OpIn:
+		values, err := toValues(c.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return clause.IN{Column: column, Values: values}, nil
+	case OpNotIn:
+		values, err := toValues(c.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return clause.Not(clause.IN{Column: column, Values: values}), nil
+	case OpContains:
+		value, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: contains expects a string", ErrInvalidFilter)
+		}
+
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{column, "%" + likeEscaper.Replace(value) + "%"}}, nil
+	case OpKeyEqual:
+		return clause.Expr{SQL: "? ->> ? = ?", Vars: []interface{}{column, c.Key, c.Value}}, nil
+	case OpHas:
+		// containment of a one item array can use a gin index on the field
+		value, err := json.Marshal([]interface{}{c.Value})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+
+		return clause.Expr{SQL: "? @> CAST(? AS jsonb)", Vars: []interface{}{column, string(value)}}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, c.Operator)
+}
+
+func (g Group) build() (clause.Expression, error) {
+	var expressions []clause.Expression
+	for _, e := range g.Expressions {
+		if e == nil {
+			continue
+		}
+
+		expression, err := e.build()
+		if err != nil {
+			return nil, err
+		}
+		if expression != nil {
+			expressions = append(expressions, expression)
+		}
+	}
+
+	// a single expression is returned as is, gorm joins a lone OR condition to its siblings with OR
+	switch len(expressions) {
+	case 0:
+		return nil, nil
+	case 1:
+		return expressions[0], nil
+	}
+
+	switch g.Match {
+	case MatchAll, "":
+		return clause.And(expressions...), nil
+	case MatchAny:
+		return clause.Or(expressions...), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown match %q", ErrInvalidFilter, g.Match)
+}
+
+func (s Subquery) build() (clause.Expression, error) {
+	for _, name := range []string{s.Field, s.Table, s.Column} {
+		if !fieldPattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, name)
+		}
+	}
+
+	query := clause.Expr{
+		SQL:  "? IN (SELECT ? FROM ?)",
+		Vars: []interface{}{clause.Column{Name: s.Field}, clause.Column{Name: s.Column}, clause.Table{Name: s.Table}},
+	}
+	if s.Where == nil {
+		return query, nil
+	}
+
+	where, err := s.Where.build()
+	if err != nil || where == nil {
+		return query, err
+	}
+
+	return clause.Expr{
+		SQL:  "? IN (SELECT ? FROM ? WHERE ?)",
+		Vars: append(query.Vars, where),
+	}, nil
+}
+
+func (s Sort) build() (clause.OrderByColumn, error) {
+	if !fieldPattern.MatchString(s.Field) {
+		return clause.OrderByColumn{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, s.Field)
+	}
+
+	return clause.OrderByColumn{Column: clause.Column{Name: s.Field}, Desc: s.Descending}, nil
+}
+
+func toValues(value interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: in expects a list of values", ErrInvalidFilter)
+	}
+
+	values := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		values = append(values, v.Index(i).Interface())
+	}
+
+	return values, nil
+}
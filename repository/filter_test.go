@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// dryRun returns a postgres connection that renders statements without sending them
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// findSQL renders the query Find runs for filter on the transactions table
+func findSQL(t *testing.T, filter Filter) (string, []interface{}, error) {
+	t.Helper()
+
+	query, err := applyFilter(dryRun(t).Table("transactions"), filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var rows []map[string]interface{}
+	stmt := query.Find(&rows).Statement
+
+	return stmt.SQL.String(), stmt.Vars, nil
+}
+
+func TestFilterSQL(t *testing.T) {
+	testCases := map[string]struct {
+		filter       Filter
+		expectedSQL  string
+		expectedVars []interface{}
+	}{
+		"no filter": {
+			filter:      Filter{},
+			expectedSQL: `SELECT * FROM "transactions"`,
+		},
+		"equal": {
+			filter:       Filter{Where: Eq("status", "success")},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" = $1`,
+			expectedVars: []interface{}{"success"},
+		},
+		"equal to nil": {
+			filter:      Filter{Where: Eq("deleted_at", nil)},
+			expectedSQL: `SELECT * FROM "transactions" WHERE "deleted_at" IS NULL`,
+		},
+		"not equal": {
+			filter:       Filter{Where: Neq("status", "failed")},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" <> $1`,
+			expectedVars: []interface{}{"failed"},
+		},
+		"greater than": {
+			filter:       Filter{Where: Gt("amount", int64(100))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "amount" > $1`,
+			expectedVars: []interface{}{int64(100)},
+		},
+		"greater than or equal": {
+			filter:       Filter{Where: Gte("amount", int64(100))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "amount" >= $1`,
+			expectedVars: []interface{}{int64(100)},
+		},
+		"less than": {
+			filter:       Filter{Where: Lt("amount", int64(100))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "amount" < $1`,
+			expectedVars: []interface{}{int64(100)},
+		},
+		"less than or equal": {
+			filter:       Filter{Where: Lte("amount", int64(100))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "amount" <= $1`,
+			expectedVars: []interface{}{int64(100)},
+		},
+		"in": {
+			filter:       Filter{Where: In("status", []string{"pending", "success"})},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" IN ($1,$2)`,
+			expectedVars: []interface{}{"pending", "success"},
+		},
+		"in a single value": {
+			filter:       Filter{Where: In("user_id", []uint{1})},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "user_id" = $1`,
+			expectedVars: []interface{}{uint(1)},
+		},
+		"not in": {
+			filter:       Filter{Where: NotIn("status", []string{"success", "refunded"})},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" NOT IN ($1,$2)`,
+			expectedVars: []interface{}{"success", "refunded"},
+		},
+		"contains escapes like wildcards": {
+			filter:       Filter{Where: Contains("description", `50%_off\`)},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "description" ILIKE $1`,
+			expectedVars: []interface{}{`%50\%\_off\\%`},
+		},
+		"key equal": {
+			filter:       Filter{Where: KeyEq("metadata", "orderId", "123")},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "metadata" ->> $1 = $2`,
+			expectedVars: []interface{}{"orderId", "123"},
+		},
+		"key equal binds keys that are not identifiers": {
+			filter:       Filter{Where: KeyEq("metadata", "x' OR '1'='1", "1")},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "metadata" ->> $1 = $2`,
+			expectedVars: []interface{}{"x' OR '1'='1", "1"},
+		},
+		"has": {
+			filter:       Filter{Where: Has("tags", "promo")},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "tags" @> CAST($1 AS jsonb)`,
+			expectedVars: []interface{}{`["promo"]`},
+		},
+		"has escapes json": {
+			filter:       Filter{Where: Has("tags", `a"]`)},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "tags" @> CAST($1 AS jsonb)`,
+			expectedVars: []interface{}{`["a\"]"]`},
+		},
+		"all": {
+			filter:       Filter{Where: All(Eq("status", "success"), Gte("amount", int64(100)))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" = $1 AND "amount" >= $2`,
+			expectedVars: []interface{}{"success", int64(100)},
+		},
+		"any": {
+			filter:       Filter{Where: Any(Eq("status", "success"), Eq("status", "failed"))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE ("status" = $1 OR "status" = $2)`,
+			expectedVars: []interface{}{"success", "failed"},
+		},
+		"any nested in all": {
+			filter: Filter{Where: All(
+				Eq("user_id", uint(1)),
+				Any(Has("tags", "promo"), Has("tags", "vip")),
+			)},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "user_id" = $1 AND ("tags" @> CAST($2 AS jsonb) OR "tags" @> CAST($3 AS jsonb))`,
+			expectedVars: []interface{}{uint(1), `["promo"]`, `["vip"]`},
+		},
+		"all nested in any": {
+			filter: Filter{Where: Any(
+				All(Eq("status", "success"), Lt("amount", int64(10))),
+				Eq("status", "failed"),
+			)},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE (("status" = $1 AND "amount" < $2) OR "status" = $3)`,
+			expectedVars: []interface{}{"success", int64(10), "failed"},
+		},
+		"group of one is not wrapped": {
+			filter:       Filter{Where: Any(Eq("status", "success"))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" = $1`,
+			expectedVars: []interface{}{"success"},
+		},
+		"empty groups and nil expressions are skipped": {
+			filter:       Filter{Where: All(nil, Any(), Eq("status", "success"))},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "status" = $1`,
+			expectedVars: []interface{}{"success"},
+		},
+		"empty group": {
+			filter:      Filter{Where: All()},
+			expectedSQL: `SELECT * FROM "transactions"`,
+		},
+		"in query": {
+			filter: Filter{Where: All(
+				Eq("status", "success"),
+				InQuery("id", "ledger_journal_entries", "transaction_id", All(Eq("type", "payment"), Gte("created_at", "2026-01-01"))),
+			)},
+			expectedSQL: `SELECT * FROM "transactions" WHERE "status" = $1 AND ` +
+				`"id" IN (SELECT "transaction_id" FROM "ledger_journal_entries" WHERE ("type" = $2 AND "created_at" >= $3))`,
+			expectedVars: []interface{}{"success", "payment", "2026-01-01"},
+		},
+		"in query without conditions": {
+			filter:      Filter{Where: InQuery("id", "ledger_journal_entries", "transaction_id", All())},
+			expectedSQL: `SELECT * FROM "transactions" WHERE "id" IN (SELECT "transaction_id" FROM "ledger_journal_entries")`,
+		},
+		"sort, limit and offset": {
+			filter: Filter{
+				Where:  Eq("user_id", uint(1)),
+				Sort:   []Sort{{Field: "created_at", Descending: true}, {Field: "id"}},
+				Limit:  10,
+				Offset: 20,
+			},
+			expectedSQL:  `SELECT * FROM "transactions" WHERE "user_id" = $1 ORDER BY "created_at" DESC,"id" LIMIT $2 OFFSET $3`,
+			expectedVars: []interface{}{uint(1), 10, 20},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sql, vars, err := findSQL(t, test.filter)
+
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, len(test.expectedVars), len(vars))
+			for i := range test.expectedVars {
+				assert.Equal(t, test.expectedVars[i], vars[i])
+			}
+		})
+	}
+}
+
+func TestFilterSQLScoped(t *testing.T) {
+	// any is kept apart from the soft delete condition of the repository
+	repo := &DatabaseRepositoryImpl[model.Transaction]{db: dryRun(t)}
+	query, err := applyFilter(repo.scoped(context.Background()), Filter{
+		Where: Any(Eq("status", "success"), Eq("status", "failed")),
+	})
+	assert.Equal(t, nil, err)
+
+	var transactions []model.Transaction
+	stmt := query.Find(&transactions).Statement
+
+	assert.Equal(t, `SELECT * FROM "transactions" WHERE "transactions"."is_deleted" = $1 AND ("status" = $2 OR "status" = $3)`, stmt.SQL.String())
+	assert.Equal(t, []interface{}{false, "success", "failed"}, stmt.Vars)
+}
+
+func TestFilterSQLInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		filter Filter
+	}{
+		"error field with a quote": {
+			filter: Filter{Where: Eq(`status" = 'x' OR "1`, "success")},
+		},
+		"error field with a statement": {
+			filter: Filter{Where: Eq("status; DROP TABLE transactions", "success")},
+		},
+		"error field with uppercase letters": {
+			filter: Filter{Where: Eq("Status", "success")},
+		},
+		"error field starting with a digit": {
+			filter: Filter{Where: Eq("1status", "success")},
+		},
+		"error qualified field": {
+			filter: Filter{Where: Eq("transactions.status", "success")},
+		},
+		"error empty field": {
+			filter: Filter{Where: Eq("", "success")},
+		},
+		"error invalid field nested in a group": {
+			filter: Filter{Where: All(Eq("status", "success"), Any(Eq("amount)", 1)))},
+		},
+		"error invalid field of a jsonb operator": {
+			filter: Filter{Where: KeyEq("metadata->>'a'", "orderId", "123")},
+		},
+		"error unknown operator": {
+			filter: Filter{Where: Condition{Field: "status", Operator: "like", Value: "s%"}},
+		},
+		"error contains without a string": {
+			filter: Filter{Where: Condition{Field: "status", Operator: OpContains, Value: 1}},
+		},
+		"error in without a list": {
+			filter: Filter{Where: In("status", "success")},
+		},
+		"error has a value that is not json": {
+			filter: Filter{Where: Has("tags", func() {})},
+		},
+		"error unknown match": {
+			filter: Filter{Where: Group{Match: "none", Expressions: []Expression{Eq("a", 1), Eq("b", 2)}}},
+		},
+		"error in query of a table with a statement": {
+			filter: Filter{Where: InQuery("id", "ledger_journal_entries; --", "transaction_id", nil)},
+		},
+		"error in query with an invalid field": {
+			filter: Filter{Where: InQuery("id", "ledger_journal_entries", "transaction_id", Eq("type)", "payment"))},
+		},
+		"error invalid sort field": {
+			filter: Filter{Sort: []Sort{{Field: "created_at DESC"}}},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := findSQL(t, test.filter)
+
+			assert.Equal(t, true, errors.Is(err, ErrInvalidFilter))
+		})
+	}
+}
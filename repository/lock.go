@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockKey marks a context whose reads lock the rows they return
+type lockKey struct{}
+
+// ForUpdate makes First and Find called with the returned context lock the rows they read against any change by
+// other database transactions, until the transaction running in ctx ends
+func ForUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockKey{}, clause.LockingStrengthUpdate)
+}
+
+// ForKeyShare makes First and Find called with the returned context keep the rows they read from being deleted or
+// locked with ForUpdate until the transaction running in ctx ends, other transactions can still update them
+func ForKeyShare(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockKey{}, "KEY SHARE")
+}
+
+// locked adds the lock asked for by ctx to query, aggregates cannot lock so Count and Stats ignore it
+func locked(ctx context.Context, query *gorm.DB) *gorm.DB {
+	if strength, ok := ctx.Value(lockKey{}).(string); ok {
+		return query.Clauses(clause.Locking{Strength: strength})
+	}
+
+	return query
+}
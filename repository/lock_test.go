@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"go-findest-rest-api/model"
+	"testing"
+)
+
+func TestLockedSQL(t *testing.T) {
+	testCases := map[string]struct {
+		ctx         context.Context
+		expectedSQL string
+	}{
+		"no lock": {
+			ctx:         context.Background(),
+			expectedSQL: `SELECT * FROM "users" WHERE "users"."is_deleted" = $1 AND "id" = $2`,
+		},
+		"for update": {
+			ctx:         ForUpdate(context.Background()),
+			expectedSQL: `SELECT * FROM "users" WHERE "users"."is_deleted" = $1 AND "id" = $2 FOR UPDATE`,
+		},
+		"for key share": {
+			ctx:         ForKeyShare(context.Background()),
+			expectedSQL: `SELECT * FROM "users" WHERE "users"."is_deleted" = $1 AND "id" = $2 FOR KEY SHARE`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repo := &DatabaseRepositoryImpl[model.User]{db: dryRun(t)}
+			query, err := applyFilter(locked(test.ctx, repo.scoped(test.ctx)), Filter{Where: Eq("id", uint(1))})
+			assert.Equal(t, nil, err)
+
+			var users []model.User
+			stmt := query.Find(&users).Statement
+
+			assert.Equal(t, test.expectedSQL, stmt.SQL.String())
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// txKey carries the database transaction a request is running in through its context
+type txKey struct{}
+
+// Transactor runs work in a database transaction, repositories called with the context passed to fn
+// take part in it and everything is rolled back when fn returns an error
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type TransactorImpl struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &TransactorImpl{
+		db: db,
+	}
+}
+
+func (t *TransactorImpl) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// a nested call joins the transaction already running
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+
+	return contextError(ctx, err)
+}
+
+// conn returns the transaction running in ctx, or the connection pool when there is none
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
+
+// Conn is conn for stores outside of this package, so their queries take part in the transaction running in ctx
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return conn(ctx, db)
+}
@@ -0,0 +1,70 @@
+package transactionservice
+
+import (
+	"encoding/json"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"time"
+)
+
+// eventValues is the state of a transaction kept in its events
+type eventValues struct {
+	Status         string         `json:"status"`
+	Amount         money.Money    `json:"amount"`
+	Currency       string         `json:"currency"`
+	RefundedAmount *money.Money   `json:"refundedAmount,omitempty"`
+	Description    string         `json:"description,omitempty"`
+	Metadata       model.Metadata `json:"metadata,omitempty"`
+	Tags           model.Tags     `json:"tags,omitempty"`
+	IsDeleted      bool           `json:"isDeleted"`
+	DeletedAt      *time.Time     `json:"deletedAt,omitempty"`
+}
+
+// NewEvent describes a change of a transaction made by actor, it also serves changes made outside of a request
+func NewEvent(actor string, requestID string, eventType string, before *model.Transaction, after *model.Transaction) (*model.TransactionEvent, error) {
+	oldValues, err := snapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := snapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionID := after.ID
+	if transactionID == 0 && before != nil {
+		transactionID = before.ID
+	}
+
+	return &model.TransactionEvent{
+		TransactionID: transactionID,
+		Type:          eventType,
+		OldValues:     oldValues,
+		NewValues:     newValues,
+		Actor:         actor,
+		RequestID:     requestID,
+	}, nil
+}
+
+func snapshot(transaction *model.Transaction) (model.JSON, error) {
+	if transaction == nil {
+		return nil, nil
+	}
+
+	values := eventValues{
+		Status:      transaction.Status,
+		Amount:      money.New(transaction.Amount, transaction.Currency),
+		Currency:    transaction.Currency,
+		Description: transaction.Description,
+		Metadata:    transaction.Metadata,
+		Tags:        transaction.Tags,
+		IsDeleted:   transaction.IsDeleted,
+		DeletedAt:   transaction.DeletedAt,
+	}
+	if transaction.RefundedAmount > 0 {
+		refunded := money.New(transaction.RefundedAmount, transaction.Currency)
+		values.RefundedAmount = &refunded
+	}
+
+	return json.Marshal(values)
+}
@@ -0,0 +1,352 @@
+package transactionservice
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/repository"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxImportRows bounds the rows of a single import, MaxImportBytes bounds the csv sent to the api
+	maxImportRows  = 100000
+	MaxImportBytes = 32 << 20
+)
+
+// ImportColumns are the fields read from every row of an import, each from the csv column of the same name
+// unless mapped to another one
+var ImportColumns = []string{"userId", "amount", "currency", "status"}
+
+var (
+	errEmptyImport    = apperror.BadRequest("empty_import", "csv holds no transactions")
+	ErrImportTooLarge = apperror.New(
+		http.StatusRequestEntityTooLarge,
+		"import_too_large",
+		fmt.Sprintf("an import can hold at most %d rows and %d MB", maxImportRows, MaxImportBytes>>20),
+	)
+)
+
+// ImportOptions configures an import. Columns maps fields of ImportColumns to the csv column holding them, Actor
+// and RequestID are recorded on the created events
+type ImportOptions struct {
+	Columns   map[string]string
+	DryRun    bool
+	Actor     string
+	RequestID string
+}
+
+// ImportRowError is a row that was not imported, Record holds its cells as they were read
+type ImportRowError struct {
+	Line   int
+	Record []string
+	Err    error
+}
+
+// ImportResult is the outcome of an import, Valid rows are the ones imported unless it was a dry run
+type ImportResult struct {
+	DryRun   bool
+	Header   []string
+	Rows     int
+	Valid    int
+	Imported int
+	Errors   []ImportRowError
+}
+
+// importRow is one row of an import, err is set once the row is known to fail
+type importRow struct {
+	line        int
+	record      []string
+	transaction *model.Transaction
+	err         error
+}
+
+// Import reads transactions from a csv with a header row and validates every row like ValidateCreate. Valid
+// rows are created along with their created events in batches of BatchSize, each batch in its own database
+// transaction. When a batch fails after others were created, its rows and the ones after it are reported with
+// the error instead. Nothing is created in a dry run
+func (s *TransactionService) Import(ctx context.Context, r io.Reader, options ImportOptions) (*ImportResult, error) {
+	header, rows, err := readImport(r, options.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	// check users and create transactions batch by batch
+	users := map[uint]bool{}
+	imported := 0
+	var batchErr error
+	for start := 0; start < len(rows) && batchErr == nil; start += BatchSize {
+		batch := rows[start:min(start+BatchSize, len(rows))]
+
+		created, err := s.importBatch(ctx, batch, users, options)
+		if err != nil && imported == 0 {
+			return nil, err
+		}
+		if err != nil {
+			batchErr = err
+			for i := start; i < len(rows); i++ {
+				if rows[i].err == nil {
+					rows[i].err = err
+				}
+			}
+		}
+		imported += created
+	}
+
+	// build result
+	result := &ImportResult{DryRun: options.DryRun, Header: header, Rows: len(rows), Imported: imported}
+	for _, row := range rows {
+		if row.err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.line, Record: row.record, Err: row.err})
+			continue
+		}
+		result.Valid++
+	}
+
+	return result, nil
+}
+
+// readImport reads the header and every row of the csv, rows are validated without the database
+func readImport(r io.Reader, columns map[string]string) ([]string, []importRow, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errEmptyImport
+	}
+	if err != nil {
+		return nil, nil, importReadError(err)
+	}
+	// spreadsheets tend to save csv with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	indexes, err := importIndexes(header, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, nil, importReadError(err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, nil, ErrImportTooLarge
+		}
+
+		row := importRow{record: record}
+		if parseErr != nil {
+			row.line = parseErr.StartLine
+			row.err = apperror.BadRequest("invalid_row", parseErr.Err.Error()).Wrap(err)
+		} else {
+			row.line, _ = reader.FieldPos(0)
+			row.transaction, row.err = importTransaction(record, indexes)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, nil, errEmptyImport
+	}
+
+	return header, rows, nil
+}
+
+func importReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrImportTooLarge
+	}
+
+	return apperror.BadRequest("invalid_csv", "request body is not valid csv").Wrap(err)
+}
+
+// importIndexes finds the csv column of every field of ImportColumns, header names are matched ignoring case
+func importIndexes(header []string, columns map[string]string) (map[string]int, error) {
+	var fields []apperror.FieldError
+	for field := range columns {
+		if !slices.Contains(ImportColumns, field) {
+			fields = append(fields, apperror.FieldError{
+				Field:   field,
+				Code:    "invalid",
+				Message: field + " is not an import column",
+			})
+		}
+	}
+
+	indexes := make(map[string]int, len(ImportColumns))
+	for _, field := range ImportColumns {
+		name := field
+		if mapped := columns[field]; mapped != "" {
+			name = mapped
+		}
+
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				indexes[field] = i
+				break
+			}
+		}
+		if _, ok := indexes[field]; !ok {
+			fields = append(fields, apperror.FieldError{
+				Field:   field,
+				Code:    "missing_column",
+				Message: fmt.Sprintf("csv has no %q column", name),
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return nil, apperror.Validation(fields...)
+	}
+
+	return indexes, nil
+}
+
+// importTransaction validates the cells of a row like the payload of a single create
+func importTransaction(record []string, indexes map[string]int) (*model.Transaction, error) {
+	cell := func(field string) string {
+		return strings.TrimSpace(record[indexes[field]])
+	}
+
+	userID, err := strconv.ParseUint(cell("userId"), 10, 0)
+	if err != nil || userID == 0 {
+		return nil, apperror.Validation(apperror.FieldError{
+			Field:   "userId",
+			Code:    "invalid",
+			Message: "userId must be a positive integer",
+		})
+	}
+
+	return ValidateCreate(dto.TransactionCreate{
+		UserID:   uint(userID),
+		Amount:   json.Number(cell("amount")),
+		Currency: cell("currency"),
+		Status:   cell("status"),
+	})
+}
+
+// importBatch checks the users of the valid rows of batch, users caches the ones already checked, and creates the
+// rows still valid unless it is a dry run. It returns how many transactions were created
+func (s *TransactionService) importBatch(ctx context.Context, batch []importRow, users map[uint]bool, options ImportOptions) (int, error) {
+	var userIDs []uint
+	for _, row := range batch {
+		if row.err != nil {
+			continue
+		}
+		if _, checked := users[row.transaction.UserID]; !checked && !slices.Contains(userIDs, row.transaction.UserID) {
+			userIDs = append(userIDs, row.transaction.UserID)
+		}
+	}
+	if len(userIDs) > 0 {
+		found, err := s.UserRepo.Find(ctx, repository.Filter{Where: repository.In("id", userIDs)})
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range userIDs {
+			users[id] = false
+		}
+		for _, user := range found {
+			users[user.ID] = true
+		}
+	}
+
+	valid := make([]model.Transaction, 0, len(batch))
+	for i := range batch {
+		if batch[i].err == nil && !users[batch[i].transaction.UserID] {
+			batch[i].err = ErrUserNotFound
+		}
+		if batch[i].err == nil {
+			valid = append(valid, *batch[i].transaction)
+		}
+	}
+	if options.DryRun || len(valid) == 0 {
+		return 0, nil
+	}
+
+	// insert transactions and their created events into database, posting the payments of those that succeeded
+	err := s.Transactor.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.LockUsers(ctx, valid); err != nil {
+			return err
+		}
+
+		created, err := s.TransactionRepo.CreateInBatches(ctx, valid, BatchSize)
+		if err != nil {
+			return err
+		}
+
+		events := make([]model.TransactionEvent, 0, len(created))
+		for i := range created {
+			event, err := NewEvent(options.Actor, options.RequestID, model.EventCreated, nil, &created[i])
+			if err != nil {
+				return err
+			}
+			events = append(events, *event)
+		}
+
+		if _, err = s.EventRepo.CreateInBatches(ctx, events, BatchSize); err != nil {
+			return err
+		}
+
+		return s.PostPayments(ctx, nil, created)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(valid), nil
+}
+
+// WriteReport writes the rows that were not imported as csv, the line of every row and its cells followed by
+// why it failed
+func (result *ImportResult) WriteReport(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{"line"}, result.Header...)
+	if err := writer.Write(append(header, "error")); err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		record := make([]string, len(result.Header))
+		copy(record, rowErr.Record)
+
+		line := append([]string{strconv.Itoa(rowErr.Line)}, record...)
+		if err := writer.Write(append(line, describeImportError(rowErr.Err))); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// describeImportError puts the message of err and of its fields on one line
+func describeImportError(err error) string {
+	appErr := apperror.From(err)
+	if len(appErr.Fields) == 0 {
+		return appErr.Message
+	}
+
+	messages := make([]string, 0, len(appErr.Fields))
+	for _, field := range appErr.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
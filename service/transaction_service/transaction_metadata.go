@@ -0,0 +1,180 @@
+package transactionservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/model"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxDescription bounds the description of a transaction, in characters
+	maxDescription = 1000
+
+	// metadata limits, keys and string values are counted in characters and the whole object in bytes
+	maxMetadataKeys  = 50
+	maxMetadataKey   = 40
+	maxMetadataValue = 500
+	maxMetadataBytes = 8 << 10
+
+	// tag limits, tags are counted in characters
+	maxTags = 20
+	maxTag  = 50
+)
+
+// MetadataKeyPattern keeps keys usable as metadata.<key> query parameters
+var MetadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tagPattern keeps tags usable in the comma separated tag query parameter, they are compared in lowercase
+var tagPattern = regexp.MustCompile(`^[a-z0-9_:.-]+$`)
+
+// Annotations are the fields clients set to link a transaction to their own records
+type Annotations struct {
+	Description string
+	Metadata    model.Metadata
+	Tags        model.Tags
+}
+
+// ParseAnnotations validates the description, metadata and tags of a created or patched transaction, tags are
+// trimmed, lowercased and deduplicated
+func ParseAnnotations(description string, metadata json.RawMessage, tags []string) (Annotations, error) {
+	var fields []apperror.FieldError
+
+	description = strings.TrimSpace(description)
+	if len([]rune(description)) > maxDescription {
+		fields = append(fields, apperror.FieldError{
+			Field:   "description",
+			Code:    "too_long",
+			Message: fmt.Sprintf("description can have at most %d characters", maxDescription),
+		})
+	}
+
+	parsedMetadata, metadataErrs := parseMetadata(metadata)
+	fields = append(fields, metadataErrs...)
+
+	parsedTags, tagErrs := parseTags(tags)
+	fields = append(fields, tagErrs...)
+
+	if len(fields) > 0 {
+		return Annotations{}, apperror.Validation(fields...)
+	}
+
+	return Annotations{Description: description, Metadata: parsedMetadata, Tags: parsedTags}, nil
+}
+
+// parseMetadata reads a flat json object of strings, numbers and booleans, a missing or null object is empty
+func parseMetadata(raw json.RawMessage) (model.Metadata, []apperror.FieldError) {
+	metadata := model.Metadata{}
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return metadata, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&metadata); err != nil {
+		return nil, []apperror.FieldError{{
+			Field:   "metadata",
+			Code:    "invalid",
+			Message: "metadata must be a json object",
+		}}
+	}
+
+	var fields []apperror.FieldError
+	if len(raw) > maxMetadataBytes {
+		fields = append(fields, apperror.FieldError{
+			Field:   "metadata",
+			Code:    "too_large",
+			Message: fmt.Sprintf("metadata can be at most %d bytes", maxMetadataBytes),
+		})
+	}
+	if len(metadata) > maxMetadataKeys {
+		fields = append(fields, apperror.FieldError{
+			Field:   "metadata",
+			Code:    "too_many_keys",
+			Message: fmt.Sprintf("metadata can have at most %d keys", maxMetadataKeys),
+		})
+	}
+
+	// report keys in order, so the same payload always gets the same errors
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field := "metadata." + key
+		if !MetadataKeyPattern.MatchString(key) || len([]rune(key)) > maxMetadataKey {
+			fields = append(fields, apperror.FieldError{
+				Field:   field,
+				Code:    "invalid_key",
+				Message: fmt.Sprintf("metadata keys can have at most %d letters, digits, _ or -", maxMetadataKey),
+			})
+			continue
+		}
+
+		switch value := metadata[key].(type) {
+		case string:
+			if len([]rune(value)) > maxMetadataValue {
+				fields = append(fields, apperror.FieldError{
+					Field:   field,
+					Code:    "too_long",
+					Message: fmt.Sprintf("metadata values can have at most %d characters", maxMetadataValue),
+				})
+			}
+		case json.Number, bool:
+		default:
+			fields = append(fields, apperror.FieldError{
+				Field:   field,
+				Code:    "invalid",
+				Message: "metadata values must be strings, numbers or booleans",
+			})
+		}
+	}
+
+	return metadata, fields
+}
+
+func parseTags(values []string) (model.Tags, []apperror.FieldError) {
+	tags := model.Tags{}
+	for _, value := range values {
+		tag := strings.ToLower(strings.TrimSpace(value))
+		if !tagPattern.MatchString(tag) || len([]rune(tag)) > maxTag {
+			return nil, []apperror.FieldError{{
+				Field:   "tags",
+				Code:    "invalid",
+				Message: fmt.Sprintf("tags can have at most %d letters, digits, _, :, . or -, got %q", maxTag, value),
+			}}
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) > maxTags {
+		return nil, []apperror.FieldError{{
+			Field:   "tags",
+			Code:    "too_many",
+			Message: fmt.Sprintf("a transaction can have at most %d tags", maxTags),
+		}}
+	}
+
+	return tags, nil
+}
+
+// Equal reports whether the transaction has the same annotations already, missing ones count as empty
+func (a Annotations) Equal(transaction model.Transaction) bool {
+	if a.Description != transaction.Description || !slices.Equal(a.Tags, transaction.Tags) {
+		return false
+	}
+	if len(a.Metadata) != len(transaction.Metadata) {
+		return false
+	}
+
+	return len(a.Metadata) == 0 || reflect.DeepEqual(a.Metadata, transaction.Metadata)
+}
@@ -0,0 +1,200 @@
+package transactionservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/dto"
+	"go-findest-rest-api/ledger"
+	"go-findest-rest-api/model"
+	"go-findest-rest-api/money"
+	"go-findest-rest-api/repository"
+	"slices"
+	"strings"
+)
+
+// BatchSize bounds a single insert statement of a bulk create or an import
+const BatchSize = 500
+
+var (
+	ErrInvalidStatus = apperror.Validation(apperror.FieldError{
+		Field:   "status",
+		Code:    "invalid",
+		Message: statusMessage(func(model.StatusRule) bool { return true }),
+	})
+	ErrInvalidInitialStatus = apperror.Validation(apperror.FieldError{
+		Field:   "status",
+		Code:    "invalid",
+		Message: statusMessage(func(rule model.StatusRule) bool { return rule.Initial }),
+	})
+	ErrUserNotFound = apperror.NotFound("user_not_found", "user not found")
+)
+
+// statusMessage lists the statuses of the state machine accepted by include, so the message follows its rules
+func statusMessage(include func(rule model.StatusRule) bool) string {
+	var statuses []string
+	for _, rule := range model.StatusRules() {
+		if include(rule) {
+			statuses = append(statuses, rule.Status)
+		}
+	}
+
+	last := len(statuses) - 1
+	return fmt.Sprintf("status must be %s or %s", strings.Join(statuses[:last], ", "), statuses[last])
+}
+
+// TransactionService holds the rules for writing transactions that do not depend on how the change was asked
+// for, so the api and the command line apply the same ones
+type TransactionService struct {
+	TransactionRepo repository.DatabaseRepository[model.Transaction]
+	UserRepo        repository.DatabaseRepository[model.User]
+	EventRepo       repository.DatabaseRepository[model.TransactionEvent]
+	Transactor      repository.Transactor
+	Ledger          ledger.Ledger
+}
+
+func NewTransactionService(
+	transactionRepo repository.DatabaseRepository[model.Transaction],
+	userRepo repository.DatabaseRepository[model.User],
+	eventRepo repository.DatabaseRepository[model.TransactionEvent],
+	transactor repository.Transactor,
+	ledger ledger.Ledger,
+) *TransactionService {
+	return &TransactionService{
+		TransactionRepo: transactionRepo,
+		UserRepo:        userRepo,
+		EventRepo:       eventRepo,
+		Transactor:      transactor,
+		Ledger:          ledger,
+	}
+}
+
+// PostPayments posts the payment journal of every transaction in after that reached success with this change,
+// before holds the transactions as they were read and is nil for new ones
+func (s *TransactionService) PostPayments(ctx context.Context, before []model.Transaction, after []model.Transaction) error {
+	previous := make(map[uint]*model.Transaction, len(before))
+	for i := range before {
+		previous[before[i].ID] = &before[i]
+	}
+
+	var journals []ledger.Journal
+	for i := range after {
+		if journal, ok := ledger.Payment(previous[after[i].ID], &after[i]); ok {
+			journals = append(journals, journal)
+		}
+	}
+	if len(journals) == 0 {
+		return nil
+	}
+
+	return s.Ledger.Post(ctx, journals...)
+}
+
+// LockUsers keeps the users of transactions about to be written from being deleted until the database transaction
+// running in ctx ends, DeleteUser locks the user it deletes too. It returns ErrUserNotFound when one is gone already
+func (s *TransactionService) LockUsers(ctx context.Context, transactions []model.Transaction) error {
+	userIDs := make([]uint, 0, len(transactions))
+	for _, transaction := range transactions {
+		if !slices.Contains(userIDs, transaction.UserID) {
+			userIDs = append(userIDs, transaction.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	users, err := s.UserRepo.Find(repository.ForKeyShare(ctx), repository.Filter{Where: repository.In("id", userIDs)})
+	if err != nil {
+		return err
+	}
+
+	found := make(map[uint]bool, len(users))
+	for _, user := range users {
+		found[user.ID] = true
+	}
+	for _, id := range userIDs {
+		if !found[id] {
+			return ErrUserNotFound
+		}
+	}
+
+	return nil
+}
+
+// ValidateCreate checks a transaction to create against the rules shared by single and bulk creates, the
+// existence of its user is left to the caller
+func ValidateCreate(payload dto.TransactionCreate) (*model.Transaction, error) {
+	if !model.IsInitialStatus(payload.Status) {
+		return nil, ErrInvalidInitialStatus
+	}
+
+	// amount is kept in the minor unit of its currency
+	amount, currency, amountErr := ParseAmount(payload.Amount, payload.Currency)
+	if amountErr != nil {
+		return nil, amountErr
+	}
+
+	// description, metadata and tags are optional
+	annotated, annotationErr := ParseAnnotations(payload.Description, payload.Metadata, payload.Tags)
+	if annotationErr != nil {
+		return nil, annotationErr
+	}
+
+	return &model.Transaction{
+		UserID:      payload.UserID,
+		Amount:      amount,
+		Currency:    currency.Code,
+		Status:      payload.Status,
+		Description: annotated.Description,
+		Metadata:    annotated.Metadata,
+		Tags:        annotated.Tags,
+	}, nil
+}
+
+// ParseAmount converts a decimal amount into minor units of an ISO 4217 currency, it must be positive
+func ParseAmount(value json.Number, code string) (int64, money.Currency, error) {
+	var fields []apperror.FieldError
+
+	currency, currencyErr := money.Lookup(code)
+	if currencyErr != nil {
+		fields = append(fields, apperror.FieldError{
+			Field:   "currency",
+			Code:    "invalid",
+			Message: "currency must be an ISO 4217 code such as IDR or JPY",
+		})
+	}
+
+	var amount int64
+	if currencyErr == nil {
+		var amountErr error
+		amount, amountErr = currency.Parse(value.String())
+		switch {
+		case errors.Is(amountErr, money.ErrTooPrecise):
+			fields = append(fields, apperror.FieldError{
+				Field:   "amount",
+				Code:    "too_precise",
+				Message: fmt.Sprintf("amount can have at most %d decimals in %s", currency.Exponent, currency.Code),
+			})
+		case amountErr != nil:
+			fields = append(fields, apperror.FieldError{
+				Field:   "amount",
+				Code:    "invalid",
+				Message: "amount must be a decimal number",
+			})
+		case amount <= 0:
+			fields = append(fields, apperror.FieldError{
+				Field:   "amount",
+				Code:    "not_positive",
+				Message: "amount must be greater than zero",
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return 0, money.Currency{}, apperror.Validation(fields...)
+	}
+
+	return amount, currency, nil
+}
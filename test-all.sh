@@ -1,5 +1,5 @@
 #!/bin/bash
 
-go test ./controller/... -cover -coverprofile=coverage.out
+go test ./... -cover -coverprofile=coverage.out
 
 go tool cover -html=coverage.out -o coverage.html
\ No newline at end of file
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+const (
+	ActorHeader = "X-Actor"
+
+	// AnonymousActor is recorded when a request does not say who made it
+	AnonymousActor = "anonymous"
+
+	maxActorLength = 255
+)
+
+// Actor reads who is making the request from the X-Actor header
+func Actor(c *gin.Context) string {
+	actor := strings.TrimSpace(c.GetHeader(ActorHeader))
+	if actor == "" {
+		return AnonymousActor
+	}
+	if runes := []rune(actor); len(runes) > maxActorLength {
+		actor = string(runes[:maxActorLength])
+	}
+
+	return actor
+}
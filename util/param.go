@@ -0,0 +1,17 @@
+package util
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"strconv"
+)
+
+// ParamID reads a positive numeric id from the path parameter called name
+func ParamID(c *gin.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, apperror.BadRequest("invalid_id", name+" must be a positive integer").Wrap(err)
+	}
+
+	return uint(id), nil
+}
@@ -2,9 +2,15 @@ package util
 
 import (
 	"github.com/gin-gonic/gin"
+	"go-findest-rest-api/apperror"
+	"go-findest-rest-api/middleware"
+	"log"
 	"net/http"
+	"strings"
 )
 
+const problemContentType = "application/problem+json"
+
 func SendResponse(c *gin.Context, statusCode int, data interface{}, message string) {
 	response := gin.H{
 		"status":  statusCode,
@@ -23,10 +29,44 @@ func Created(c *gin.Context, message string, data interface{}) {
 	SendResponse(c, http.StatusCreated, data, message)
 }
 
-func NotFound(c *gin.Context, message string, data interface{}) {
-	SendResponse(c, http.StatusNotFound, data, message)
-}
+// Error responds with the status and code of err, internal causes are logged but never sent to the client.
+// Clients accepting application/problem+json get an RFC 7807 body instead of the usual envelope.
+func Error(c *gin.Context, err error) {
+	appErr := apperror.From(err)
+	if appErr.Status >= http.StatusInternalServerError && appErr.Err != nil {
+		log.Printf("%s %s [%s]: %v", c.Request.Method, c.Request.URL.Path, middleware.RequestIDFrom(c.Request.Context()), appErr.Err)
+	}
+
+	fields := appErr.Fields
+	if fields == nil {
+		fields = []apperror.FieldError{}
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), problemContentType) {
+		title := http.StatusText(appErr.Status)
+		if appErr.Status == apperror.StatusClientClosedRequest {
+			title = "Client Closed Request"
+		}
+
+		// gin keeps a content type that is already set
+		c.Header("Content-Type", problemContentType)
+		c.JSON(appErr.Status, gin.H{
+			"type":     "about:blank",
+			"title":    title,
+			"status":   appErr.Status,
+			"detail":   appErr.Message,
+			"instance": c.Request.URL.Path,
+			"code":     appErr.Code,
+			"errors":   fields,
+		})
+		return
+	}
 
-func InternalServerError(c *gin.Context, message string, data interface{}) {
-	SendResponse(c, http.StatusInternalServerError, data, message)
+	c.JSON(appErr.Status, gin.H{
+		"status":  appErr.Status,
+		"message": appErr.Message,
+		"data":    nil,
+		"code":    appErr.Code,
+		"errors":  fields,
+	})
 }
@@ -0,0 +1,16 @@
+package util
+
+import (
+	"errors"
+	"time"
+)
+
+// LoadTimezone loads an IANA time zone by name. Local is refused, it names the zone of the server rather than
+// one the client can rely on and the database does not know it
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, errors.New("unknown time zone Local")
+	}
+
+	return time.LoadLocation(name)
+}